@@ -0,0 +1,110 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+// binarySniffLen 判断内容是否为二进制时检查的字节数
+const binarySniffLen = 512
+
+// isZipArchive 判断文件是否为 zip 格式的归档（.zip/.jar）
+func isZipArchive(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".zip" || ext == ".jar"
+}
+
+// isTarArchive 判断文件是否为 gzip 压缩的 tar 归档（.tar.gz/.tgz）
+func isTarArchive(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// isSearchableArchive 判断文件是否为按 -archive / -tar 需要展开搜索的归档
+func isSearchableArchive(config *Config, name string) bool {
+	return (config.Archive && isZipArchive(name)) || (config.Tar && isTarArchive(name))
+}
+
+// searchInZip 搜索 zip/jar 归档中文件名符合 -f 的文本条目，输出格式为 archive!entry，返回匹配行数
+func searchInZip(archivePath string, file *os.File, showName bool, searcher *Searcher) int {
+	info, err := file.Stat()
+	if err != nil {
+		log.Printf("Error reading archive %s: %v\n", archivePath, err)
+		return 0
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		log.Printf("Error reading archive %s: %v\n", archivePath, err)
+		return 0
+	}
+
+	// 归档内通常有多个条目，除非显式指定 -h，否则始终显示条目名
+	showName = showName || searcher.Config.FileNameMode != fileNameNever
+	archivePath = "./" + strings.ReplaceAll(archivePath, "\\", "/")
+
+	matches := 0
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() || !matchFileName(searcher.FileRegex, path.Base(entry.Name)) {
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			log.Printf("Error opening entry %s!%s: %v\n", archivePath, entry.Name, err)
+			continue
+		}
+		content := bufio.NewReader(rc)
+		if !isBinary(content) {
+			matches += searchReader(archivePath+"!"+entry.Name, content, showName, searcher)
+		}
+		rc.Close()
+	}
+	return matches
+}
+
+// isBinary 根据开头内容是否包含 NUL 字节判断是否为二进制内容
+func isBinary(reader *bufio.Reader) bool {
+	head, _ := reader.Peek(binarySniffLen)
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// searchInTar 搜索 tar.gz 归档中文件名符合 -f 的文本条目，输出格式为 archive!member，返回匹配行数
+func searchInTar(archivePath string, reader io.Reader, showName bool, searcher *Searcher) int {
+	archivePath = "./" + strings.ReplaceAll(archivePath, "\\", "/")
+	gz, err := getGzipReader(reader)
+	if err != nil {
+		log.Printf("Error reading archive %s: %v\n", archivePath, err)
+		return 0
+	}
+	defer putGzipReader(gz)
+
+	showName = showName || searcher.Config.FileNameMode != fileNameNever
+	matches := 0
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("Error reading archive %s: %v\n", archivePath, err)
+			break
+		}
+		if header.Typeflag != tar.TypeReg || !matchFileName(searcher.FileRegex, path.Base(header.Name)) {
+			continue
+		}
+
+		content := bufio.NewReader(tr)
+		if !isBinary(content) {
+			matches += searchReader(archivePath+"!"+header.Name, content, showName, searcher)
+		}
+	}
+	return matches
+}
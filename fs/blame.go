@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// blameLines 对文件的指定行执行一次 git blame --porcelain（每行一个 -L），返回行号到“作者 短 SHA”的映射；
+// 文件不在 git 仓库中、不是普通文件（如压缩包内条目）、为 -gz 解压的文件或 git 执行失败时返回 nil
+func blameLines(searcher *Searcher, path string, lines []int) map[int]string {
+	if searcher.Config.Gzip && isGzipFile(path) {
+		return nil
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	args := []string{"-C", filepath.Dir(path), "blame", "--porcelain"}
+	for _, line := range lines {
+		args = append(args, "-L", strconv.Itoa(line)+",+1")
+	}
+	args = append(args, "--", filepath.Base(path))
+	out, err := exec.CommandContext(searcher.Ctx, "git", args...).Output()
+	if err != nil {
+		return nil
+	}
+
+	// 同一提交的 author 等信息只在其第一次出现时给出，因此先记录每行所属的提交
+	authors := make(map[string]string)
+	commits := make(map[int]string)
+	var sha string
+	var final int
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			commits[final] = sha
+		case strings.HasPrefix(line, "author "):
+			authors[sha] = strings.TrimPrefix(line, "author ")
+		default:
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && len(fields[0]) == 40 {
+				sha = fields[0]
+				final, _ = strconv.Atoi(fields[2])
+			}
+		}
+	}
+
+	blame := make(map[int]string, len(commits))
+	for line, sha := range commits {
+		blame[line] = authors[sha] + " " + sha[:7]
+	}
+	return blame
+}
+
+// printBlamed 为一个文件中收集的匹配添加 -blame 注释后输出，无法注释时原样输出
+func printBlamed(searcher *Searcher, path string, matches []Match, showName bool) {
+	if len(matches) == 0 {
+		return
+	}
+	lines := make([]int, len(matches))
+	for i, match := range matches {
+		lines[i] = match.Line
+	}
+	blame := blameLines(searcher, path, lines)
+	for _, match := range matches {
+		match.Blame = blame[match.Line]
+		searcher.Printer.PrintMatch(match, showName)
+	}
+}
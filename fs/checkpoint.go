@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// checkpoint 实现 -checkpoint / -resume：目录在遍历离开且其下所有文件搜索完成后视为完成，立即以一行追加写入检查点文件；
+// -resume 时跳过文件中记录的目录，已在其中输出过的匹配不会重复输出。
+//
+// pending 记录每个未完成目录尚未结束的事项数：遍历仍在其中（1）、已调度但未搜索完的文件、未完成的子目录
+type checkpoint struct {
+	mu        sync.Mutex
+	file      *os.File
+	stopped   func() bool
+	completed map[string]bool
+	pending   map[string]int
+	open      []string // 遍历当前所在的目录，由外到内
+}
+
+// openCheckpoint 打开检查点文件；resume 时先读入已完成的目录并在文件末尾追加，否则清空文件
+func openCheckpoint(path string, resume bool, stopped func() bool) (*checkpoint, error) {
+	c := &checkpoint{stopped: stopped, completed: make(map[string]bool), pending: make(map[string]int)}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if file, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				if dir := strings.TrimSpace(scanner.Text()); dir != "" {
+					c.completed[dir] = true
+				}
+			}
+			file.Close()
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	c.file = file
+	return c, nil
+}
+
+// within 判断 path 是否为 dir 本身或位于 dir 之下
+func within(path, dir string) bool {
+	if dir == "." {
+		return !filepath.IsAbs(path)
+	}
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// walkTo 在遍历到 path 时调用，结束所有不包含 path 的目录的遍历
+func (c *checkpoint) walkTo(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path = filepath.Clean(path)
+	for len(c.open) > 0 && !within(path, c.open[len(c.open)-1]) {
+		dir := c.open[len(c.open)-1]
+		c.open = c.open[:len(c.open)-1]
+		c.finish(dir)
+	}
+}
+
+// enter 记录遍历进入目录，-resume 时已完成的目录返回 false，调用方应跳过该目录
+func (c *checkpoint) enter(dir string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dir = filepath.Clean(dir)
+	if c.completed[dir] {
+		return false
+	}
+	if len(c.open) > 0 {
+		c.pending[c.open[len(c.open)-1]]++
+	}
+	c.pending[dir] = 1
+	c.open = append(c.open, dir)
+	return true
+}
+
+// add 记录一个将被搜索的文件
+func (c *checkpoint) add(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[filepath.Dir(path)]++
+}
+
+// done 记录一个文件搜索结束
+func (c *checkpoint) done(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finish(filepath.Dir(path))
+}
+
+// close 在遍历结束后调用，结束所有目录的遍历；文件须在所有搜索完成后调用 closeFile 关闭
+func (c *checkpoint) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.open) > 0 {
+		dir := c.open[len(c.open)-1]
+		c.open = c.open[:len(c.open)-1]
+		c.finish(dir)
+	}
+}
+
+// closeFile 关闭检查点文件
+func (c *checkpoint) closeFile() {
+	if err := c.file.Close(); err != nil {
+		log.Printf("Error writing checkpoint: %v\n", err)
+	}
+}
+
+// finish 结束目录的一个事项，目录完成时写入检查点并结束其父目录的一个事项；调用方需持有锁。
+// 搜索被取消（-stop-on-first、-deadline 等）时不再写入，此时目录未必已完整搜索
+func (c *checkpoint) finish(dir string) {
+	count, ok := c.pending[dir]
+	if !ok {
+		return
+	}
+	if count > 1 {
+		c.pending[dir] = count - 1
+		return
+	}
+	delete(c.pending, dir)
+	if c.stopped() {
+		return
+	}
+	if _, err := fmt.Fprintln(c.file, dir); err != nil {
+		log.Printf("Error writing checkpoint: %v\n", err)
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		c.finish(parent)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"strings"
+	"unicode/utf8"
+)
+
+// namedEncoding 为 -encoding 中的一项，encoding 为 nil 表示 UTF-8
+type namedEncoding struct {
+	name     string
+	encoding encoding.Encoding
+}
+
+// byteOrderMarks 按 BOM 识别的编码，存在 BOM 时优先于 -encoding 列表
+var byteOrderMarks = []struct {
+	bom      []byte
+	encoding namedEncoding
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, namedEncoding{"utf-8", nil}},
+	{[]byte{0xFF, 0xFE}, namedEncoding{"utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)}},
+	{[]byte{0xFE, 0xFF}, namedEncoding{"utf-16be", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)}},
+}
+
+// parseEncodings 解析逗号分隔的编码列表，除常用别名外其余名称按 WHATWG 编码标签查找（如 gbk、shift_jis）
+func parseEncodings(list string) ([]namedEncoding, error) {
+	var encodings []namedEncoding
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		var enc encoding.Encoding
+		switch name {
+		case "utf-8", "utf8":
+		case "utf-16", "utf-16le":
+			enc = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+		case "utf-16be":
+			enc = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+		case "latin1", "iso-8859-1":
+			enc = charmap.ISO8859_1
+		default:
+			var err error
+			if enc, err = htmlindex.Get(name); err != nil {
+				return nil, fmt.Errorf("unsupported encoding %q", name)
+			}
+		}
+		encodings = append(encodings, namedEncoding{name, enc})
+	}
+	return encodings, nil
+}
+
+// decodeContent 将文件内容解码为 UTF-8：有 BOM 时按 BOM 解码，否则按列表顺序尝试，
+// 返回第一个能完整解码的结果
+func decodeContent(data []byte, encodings []namedEncoding) ([]byte, error) {
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(data, mark.bom) {
+			if decoded, ok := tryDecode(data[len(mark.bom):], mark.encoding); ok {
+				return decoded, nil
+			}
+			return nil, fmt.Errorf("invalid %s content after BOM", mark.encoding.name)
+		}
+	}
+
+	for _, enc := range encodings {
+		if decoded, ok := tryDecode(data, enc); ok {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("content is not valid in any of the requested encodings")
+}
+
+// tryDecode 使用指定编码解码，出现错误或无法映射的字符时视为失败
+func tryDecode(data []byte, enc namedEncoding) ([]byte, bool) {
+	if enc.encoding == nil {
+		return data, utf8.Valid(data)
+	}
+	if strings.HasPrefix(enc.name, "utf-16") && len(data)%2 != 0 {
+		return nil, false
+	}
+
+	decoded, err := enc.encoding.NewDecoder().Bytes(data)
+	if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+		return nil, false
+	}
+	return decoded, true
+}
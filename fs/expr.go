@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// exprParser 递归下降解析 -expr 的布尔表达式，语法如下（优先级 ! 高于 &&，&& 高于 ||）：
+//
+//	expr    = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | primary
+//	primary = "(" expr ")" | "contains" "(" string ")" | "regex" "(" string ")"
+//
+// 字符串用单引号或双引号括起，\ 仅转义引号及 \ 本身，其余 \ 原样保留以便书写正则
+type exprParser struct {
+	src string
+	pos int
+}
+
+// parseExpr 将 -expr 表达式解析为逐行判断的谓词
+func parseExpr(src string) (func(string) bool, error) {
+	p := &exprParser{src: src}
+	predicate, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return predicate, nil
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid -expr at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// consume 跳过空白后，若接下来的内容为 token 则将其消费并返回 true
+func (p *exprParser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (func(string) bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		either := left
+		left = func(line string) bool { return either(line) || right(line) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (func(string) bool, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		both := left
+		left = func(line string) bool { return both(line) && right(line) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (func(string) bool, error) {
+	if p.consume("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(line string) bool { return !operand(line) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (func(string) bool, error) {
+	if p.consume("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing )")
+		}
+		return inner, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name != "contains" && name != "regex" {
+		p.pos = start
+		return nil, p.errorf("expected contains(...), regex(...), ! or (")
+	}
+	if !p.consume("(") {
+		return nil, p.errorf("missing ( after %s", name)
+	}
+	arg, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume(")") {
+		return nil, p.errorf("missing ) after %s argument", name)
+	}
+
+	if name == "contains" {
+		return func(line string) bool { return strings.Contains(line, arg) }, nil
+	}
+	if _, err := regexp2.Compile(arg, regexp2.None); err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return createPatternMatcher("", arg), nil
+}
+
+func (p *exprParser) parseString() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) || (p.src[p.pos] != '\'' && p.src[p.pos] != '"') {
+		return "", p.errorf("expected a quoted string")
+	}
+	quote := p.src[p.pos]
+	p.pos++
+	var value strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return value.String(), nil
+		case c == '\\' && p.pos < len(p.src) && (p.src[p.pos] == quote || p.src[p.pos] == '\\'):
+			value.WriteByte(p.src[p.pos])
+			p.pos++
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
@@ -0,0 +1,41 @@
+package main
+
+// fuzzyContains 判断 text 中是否存在与 pattern 的编辑距离（Levenshtein）不超过 k 的子串。
+//
+// 采用 Sellers 算法：按行逐字符更新 pattern 各前缀与以当前字符结尾的子串之间的最小编辑距离，子串可从任意位置开始；
+// 并按 Ukkonen 的截断只计算距离可能不超过 k 的前缀（超出部分固定为 k+1），期望复杂度为 O(k·len(text))，
+// 最坏为 O(len(pattern)·len(text))，远慢于 -s 的子串查找
+func fuzzyContains(text string, pattern []rune, k int) bool {
+	m := len(pattern)
+	if m <= k {
+		return true
+	}
+	cost := make([]int, m+1)
+	for i := range cost {
+		cost[i] = minInt(i, k+1)
+	}
+	// last 为距离不超过 k 的最长前缀，更长的前缀距离均为 k+1
+	last := k
+	for _, c := range text {
+		// diag 为上一字符处前缀 i-1 的距离，left 为当前字符处前缀 i-1 的距离；空前缀的距离始终为 0
+		diag, left := 0, 0
+		top := minInt(last+1, m)
+		for i := 1; i <= top; i++ {
+			same := cost[i]
+			d := diag
+			if pattern[i-1] != c {
+				d++
+			}
+			d = minInt(d, minInt(same, left)+1)
+			d = minInt(d, k+1)
+			diag, left = same, d
+			cost[i] = d
+		}
+		if top == m && cost[m] <= k {
+			return true
+		}
+		for last = top; last > 0 && cost[last] > k; last-- {
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// listTrackedFiles 通过 git ls-files 列出 root 下被 git 跟踪且仍存在的文件
+func listTrackedFiles(root string) ([]string, error) {
+	out, err := exec.Command("git", "-C", root, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range bytes.Split(out, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		path := filepath.Join(root, filepath.FromSlash(string(name)))
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// addedLine 表示 git diff 中新增的一行
+type addedLine struct {
+	lineNo int
+	text   string
+}
+
+// searchDiff 仅在工作区未提交改动（相对 HEAD）新增的行中搜索，输出格式为 path:line +text
+func searchDiff(searcher *Searcher) {
+	config := searcher.Config
+	out, err := exec.CommandContext(searcher.Ctx, "git", "-C", config.SearchPath, "-c", "core.quotePath=false",
+		"diff", "HEAD", "--no-color", "--no-ext-diff", "--relative", "-U0").Output()
+	if err != nil {
+		log.Printf("Error running git diff in %s: %v\n", config.SearchPath, err)
+		return
+	}
+
+	files, order := parseAddedLines(out)
+	for _, name := range order {
+		if searcher.stopped() {
+			break
+		}
+		path := filepath.Join(config.SearchPath, filepath.FromSlash(name))
+		if isExcluded(path, config.ExclusionPaths) || !matchFile(searcher, path) {
+			continue
+		}
+
+		path = "./" + strings.ReplaceAll(path, "\\", "/")
+		for _, added := range files[name] {
+			line := added.text
+			if config.NonBlank && strings.TrimSpace(line) == "" {
+				continue
+			}
+			if !searcher.Matcher(line) || !searcher.claimMatch() {
+				continue
+			}
+			column, _ := searcher.Locator(line)
+			if searcher.Replacer != nil {
+				line = searcher.Replacer(line)
+			}
+			searcher.Printer.PrintMatch(Match{Path: path, Line: added.lineNo, Col: column + 1, Text: line}, true)
+		}
+	}
+}
+
+// parseAddedLines 解析 -U0 格式的 unified diff，返回每个文件新增的行及文件出现顺序；
+// 按 @@ 中的行数统计每个 hunk 的范围，hunk 内以 +++ 或 --- 开头的内容行不会被当作文件头
+func parseAddedLines(diff []byte) (map[string][]addedLine, []string) {
+	files := make(map[string][]addedLine)
+	var order []string
+	var current string
+	lineNo, oldLeft, newLeft := 0, 0, 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if current != "" {
+					files[current] = append(files[current], addedLine{lineNo, strings.TrimRight(line[1:], "\r")})
+				}
+				lineNo++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, " "):
+				lineNo++
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current = ""
+		case strings.HasPrefix(line, "+++ "):
+			current = ""
+			if name := strings.TrimPrefix(line, "+++ "); name != "/dev/null" {
+				current = strings.TrimPrefix(name, "b/")
+				order = append(order, current)
+			}
+		case strings.HasPrefix(line, "@@ "):
+			lineNo, oldLeft, newLeft = parseHunk(line)
+		}
+	}
+	return files, order
+}
+
+// parseHunk 从 "@@ -a,b +c,d @@" 中解析新文件的起始行号 c 以及旧、新两侧的行数 b、d（省略时为 1）
+func parseHunk(header string) (start, oldCount, newCount int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0
+	}
+	_, oldCount = parseRange(fields[1][1:])
+	start, newCount = parseRange(fields[2][1:])
+	return start, oldCount, newCount
+}
+
+// parseRange 解析 hunk 范围 "start,count"，省略 count 时为 1
+func parseRange(r string) (start, count int) {
+	first, rest, found := strings.Cut(r, ",")
+	start, _ = strconv.Atoi(first)
+	count = 1
+	if found {
+		count, _ = strconv.Atoi(rest)
+	}
+	return start, count
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// gzipReaders 缓存用过的 gzip.Reader，通过 Reset 复用其内部的解压窗口，避免每个文件重新分配
+var gzipReaders sync.Pool
+
+// lineBuffers 缓存 searchReader 的行缓冲区；缓冲区大小固定为 bufio.MaxScanTokenSize，
+// 与默认的单行上限一致，因此每个并发搜索最多占用一个缓冲区
+var lineBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, bufio.MaxScanTokenSize)
+		return &buf
+	},
+}
+
+// isGzipFile 判断文件是否为单个 gzip 压缩文件（.gz，不含 .tar.gz）
+func isGzipFile(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".gz") && !isTarArchive(name)
+}
+
+// matchGzipFile 判断 -gz 时压缩文件去掉 .gz 后的文件名是否符合 -f
+func matchGzipFile(searcher *Searcher, path string) bool {
+	return searcher.Config.Gzip && isGzipFile(path) && matchFile(searcher, path[:len(path)-len(".gz")])
+}
+
+// getGzipReader 从缓存中取出 gzip.Reader 并重置为读取 reader，缓存为空时新建
+func getGzipReader(reader io.Reader) (*gzip.Reader, error) {
+	gz, ok := gzipReaders.Get().(*gzip.Reader)
+	if !ok {
+		return gzip.NewReader(reader)
+	}
+	if err := gz.Reset(reader); err != nil {
+		gzipReaders.Put(gz)
+		return nil, err
+	}
+	return gz, nil
+}
+
+// putGzipReader 关闭 gzip.Reader 并放回缓存
+func putGzipReader(gz *gzip.Reader) {
+	gz.Close()
+	gzipReaders.Put(gz)
+}
+
+// searchInGzip 透明解压 .gz 文件并搜索其内容，输出的路径仍为压缩文件本身，返回匹配行数
+func searchInGzip(gzPath string, reader io.Reader, showName bool, searcher *Searcher) int {
+	gzPath = "./" + strings.ReplaceAll(gzPath, "\\", "/")
+	gz, err := getGzipReader(reader)
+	if err != nil {
+		log.Printf("Error reading file %s: %v\n", gzPath, err)
+		return 0
+	}
+	defer putGzipReader(gz)
+
+	content := bufio.NewReader(gz)
+	if isBinary(content) {
+		return 0
+	}
+	return searchReader(gzPath, content, showName, searcher)
+}
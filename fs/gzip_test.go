@@ -0,0 +1,54 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGzipLogs 在 root 下生成 files 个 gzip 压缩的日志文件，每个 lines 行
+func writeGzipLogs(tb testing.TB, root string, files, lines int) {
+	tb.Helper()
+	for f := 0; f < files; f++ {
+		file, err := os.Create(filepath.Join(root, fmt.Sprintf("app%03d.log.gz", f)))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		gz := gzip.NewWriter(file)
+		for l := 0; l < lines; l++ {
+			level := "INFO"
+			if l%50 == 0 {
+				level = "ERROR"
+			}
+			fmt.Fprintf(gz, "2024-01-01T00:00:%02d level=%s request=%d path=/api/items/%d\n", l%60, level, f*lines+l, l)
+		}
+		if err := gz.Close(); err != nil {
+			tb.Fatal(err)
+		}
+		if err := file.Close(); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestSearchInGzip(t *testing.T) {
+	root := t.TempDir()
+	writeGzipLogs(t, root, 2, 100)
+	config := parseArgs(t, "-f", `\.log$`, "-s", "level=ERROR", "-gz", "-P", "1", root)
+	output := captureStdout(t, func() { search(config) })
+	for _, name := range []string{"app000.log.gz", "app001.log.gz"} {
+		if !strings.Contains(output, name) {
+			t.Errorf("no match reported for %s:\n%s", name, output)
+		}
+	}
+}
+
+// BenchmarkSearchGzip 在默认并发下搜索压缩日志，B/op 反映 gzip.Reader 与行缓冲区复用后的内存占用
+func BenchmarkSearchGzip(b *testing.B) {
+	root := b.TempDir()
+	writeGzipLogs(b, root, 200, 2000)
+	benchmarkSearch(b, root, "-f", `\.log$`, "-s", "level=ERROR", "-gz")
+}
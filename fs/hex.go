@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// hexChunkSize -hex 每次读取的字节数
+const hexChunkSize = 64 * 1024
+
+// parseHexPattern 将 -hex 下 -s 的值解码为字节序列，允许空白及 0x 前缀，如 "EF BB BF" 或 0xefbbbf
+func parseHexPattern(value string) ([]byte, error) {
+	value = strings.Join(strings.Fields(value), "")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	pattern, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex pattern %q: %v", value, err)
+	}
+	if len(pattern) == 0 {
+		return nil, fmt.Errorf("empty hex pattern")
+	}
+	return pattern, nil
+}
+
+// searchBytes 在原始字节中查找 -hex 序列，不按行切分，输出每个（不重叠）匹配的字节偏移；
+// 分块读取时保留上一块末尾 len(pattern)-1 个字节，以找到跨块的匹配
+func searchBytes(path string, reader io.Reader, searcher *Searcher) int {
+	pattern := searcher.Config.HexPattern
+	chunk := make([]byte, hexChunkSize)
+	var buf []byte
+	var base int64 // buf[0] 在文件中的偏移
+	matches := 0
+	for !searcher.stopped() {
+		n, err := reader.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+
+		start := 0
+		for {
+			i := bytes.Index(buf[start:], pattern)
+			if i < 0 {
+				break
+			}
+			if !searcher.claimMatch() {
+				return matches
+			}
+			matches++
+			offset := base + int64(start+i)
+			searcher.Printer.PrintMatch(Match{Path: path, Text: fmt.Sprintf("byte offset %d (0x%x)", offset, offset)}, true)
+			start += i + len(pattern)
+		}
+
+		cut := len(buf) - (len(pattern) - 1)
+		if cut < start {
+			cut = start
+		}
+		if cut > 0 {
+			base += int64(cut)
+			buf = append(buf[:0], buf[cut:]...)
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("Error reading file %s: %v\n", path, err)
+			break
+		}
+	}
+
+	if searcher.Config.JSONSummary {
+		recordExtension(searcher.Summary, path, matches)
+	}
+	return matches
+}
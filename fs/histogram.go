@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// histogramWidth 直方图中最长一条的字符数
+const histogramWidth = 50
+
+// recordTimestamp 按 -histogram 的时间格式解析匹配行开头的时间戳（取与格式等长的前缀），
+// 计入 -bucket 对应的时间区间，无法解析的计入 Unparsed
+func recordTimestamp(summary *Summary, config *Config, line string) {
+	var bucket time.Time
+	parsed := false
+	if len(line) >= len(config.Histogram) {
+		if t, err := time.Parse(config.Histogram, line[:len(config.Histogram)]); err == nil {
+			bucket, parsed = t.Truncate(config.Bucket), true
+		}
+	}
+
+	summary.mu.Lock()
+	defer summary.mu.Unlock()
+	if parsed {
+		summary.Histogram[bucket]++
+	} else {
+		summary.Unparsed++
+	}
+}
+
+// printHistogram 按时间顺序输出每个区间的匹配数及按比例缩放的条形
+func printHistogram(summary *Summary) {
+	if len(summary.Histogram) == 0 && summary.Unparsed == 0 {
+		return
+	}
+	buckets := make([]time.Time, 0, len(summary.Histogram))
+	peak := summary.Unparsed
+	for bucket, count := range summary.Histogram {
+		buckets = append(buckets, bucket)
+		if count > peak {
+			peak = count
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Before(buckets[j]) })
+
+	bar := func(count int) string {
+		return strings.Repeat("#", (count*histogramWidth+peak-1)/peak)
+	}
+	fmt.Printf("\nMatches over time:\n")
+	for _, bucket := range buckets {
+		count := summary.Histogram[bucket]
+		fmt.Printf("%s\t%8d\t%s\n", bucket.Format("2006-01-02 15:04:05"), count, bar(count))
+	}
+	if summary.Unparsed > 0 {
+		fmt.Printf("%-19s\t%8d\t%s\n", "unparsed", summary.Unparsed, bar(summary.Unparsed))
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "strings"
+
+// keyLevel 为一层 YAML 键及其缩进
+type keyLevel struct {
+	indent int
+	key    string
+}
+
+// keyPathTracker 按缩进跟踪 YAML 风格文件中的键嵌套，用于 -keypath
+type keyPathTracker struct {
+	stack []keyLevel
+}
+
+// feed 处理一行内容：弹出缩进不小于当前行的键，当前行定义了键时压入；空行和注释行不影响嵌套
+func (t *keyPathTracker) feed(line string) {
+	rest := strings.TrimLeft(line, " ")
+	indent := len(line) - len(rest)
+	// 列表项 "- key: value" 的键相对于短横线再缩进一级
+	if strings.HasPrefix(rest, "- ") {
+		trimmed := strings.TrimLeft(rest[1:], " ")
+		indent += len(rest) - len(trimmed)
+		rest = trimmed
+	}
+	if rest == "" || strings.HasPrefix(rest, "#") {
+		return
+	}
+
+	for len(t.stack) > 0 && t.stack[len(t.stack)-1].indent >= indent {
+		t.stack = t.stack[:len(t.stack)-1]
+	}
+	if key := yamlKey(rest); key != "" {
+		t.stack = append(t.stack, keyLevel{indent, key})
+	}
+}
+
+// path 返回以 . 连接的当前键路径
+func (t *keyPathTracker) path() string {
+	keys := make([]string, len(t.stack))
+	for i, level := range t.stack {
+		keys[i] = level.key
+	}
+	return strings.Join(keys, ".")
+}
+
+// yamlKey 提取 "key: value" 或 "key:" 中的键，去掉引号；不是键值行时返回空串
+func yamlKey(text string) string {
+	i := strings.Index(text, ":")
+	if i <= 0 || (i+1 < len(text) && text[i+1] != ' ' && text[i+1] != '\t') {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(text[:i]), `"'`)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// 自适应并发（-P auto）的调整参数
+const (
+	ioRatioAlpha     = 0.2 // I/O 耗时占比移动平均的平滑系数
+	ioBoundRatio     = 0.5 // 平均占比高于该值时视为 I/O 密集，增加并发
+	cpuBoundRatio    = 0.2 // 平均占比低于该值时视为 CPU 密集，减少并发
+	adjustSampleSize = 16  // 每累计多少个文件样本调整一次并发数
+)
+
+// adaptiveLimiter 是并发上限可动态调整的信号量，用于 -P auto。
+//
+// 启发式：初始并发数为 CPU 数。每个文件搜索结束后，记录其 open/read 耗时占总耗时的
+// 比例，并计算指数移动平均。每累计 adjustSampleSize 个样本检查一次：平均占比高于
+// ioBoundRatio 说明 worker 大部分时间阻塞在 I/O 上（如网络文件系统），并发数增加一个
+// CPU 数，直到上限；低于 cpuBoundRatio 说明瓶颈在正则匹配等 CPU 计算上（如本地 SSD），
+// 并发数减少一个 CPU 数，直到下限。介于两者之间时保持不变。
+type adaptiveLimiter struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	active  int
+	limit   int
+	step    int
+	max     int
+	ioRatio float64
+	samples int
+}
+
+// newAdaptiveLimiter 创建以 min 为初始值及下限、max 为上限的自适应信号量
+func newAdaptiveLimiter(min, max int) *adaptiveLimiter {
+	if max < min {
+		max = min
+	}
+	l := &adaptiveLimiter{limit: min, step: min, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire 获取一个并发名额，达到当前上限时阻塞
+func (l *adaptiveLimiter) acquire() {
+	l.mu.Lock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	l.mu.Unlock()
+}
+
+// release 释放一个并发名额
+func (l *adaptiveLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// observe 记录一个文件的 I/O 耗时与总耗时，并按需调整并发上限
+func (l *adaptiveLimiter) observe(ioTime, total time.Duration) {
+	if total <= 0 {
+		return
+	}
+	ratio := float64(ioTime) / float64(total)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.samples == 0 && l.ioRatio == 0 {
+		l.ioRatio = ratio
+	} else {
+		l.ioRatio = ioRatioAlpha*ratio + (1-ioRatioAlpha)*l.ioRatio
+	}
+
+	if l.samples++; l.samples < adjustSampleSize {
+		return
+	}
+	l.samples = 0
+	switch {
+	case l.ioRatio > ioBoundRatio && l.limit < l.max:
+		l.limit = minInt(l.limit+l.step, l.max)
+		l.cond.Broadcast()
+	case l.ioRatio < cpuBoundRatio && l.limit > l.step:
+		l.limit = maxInt(l.limit-l.step, l.step)
+	}
+}
+
+// timedReader 统计底层 Read 调用的累计耗时
+type timedReader struct {
+	reader  io.Reader
+	elapsed time.Duration
+}
+
+func (r *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := r.reader.Read(p)
+	r.elapsed += time.Since(start)
+	return n, err
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
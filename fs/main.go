@@ -2,40 +2,267 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/dlclark/regexp2"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
 // Config 结构体集中管理命令行参数和配置信息
 type Config struct {
 	FilePattern        string
+	PathPattern        string
 	SearchPattern      string
 	SearchRegexPattern string
-	ExclusionPath      string
+	ExclusionPaths     []string
 	Module             int
 	Parallelism        int
+	AutoParallelism    bool
 	SearchPath         string
+	CheckEOL           bool
+	JSONSummary        bool
+	FileNameMode       int
+	Replacement        string
+	NearPatterns       []string
+	NearDistance       int
+	Archive            bool
+	NonBlank           bool
+	Encodings          []namedEncoding
+	StatsByDir         bool
+	Tracked            bool
+	Diff               bool
+	VimGrep            bool
+	NullData           bool
+	Sequential         bool
+	Ordered            bool
+	NotPattern         string
+	Separate           bool
+	Separator          string
+	StopOnFirst        bool
+	Format             string
+	Ranges             map[string][]lineRange
+	Trim               bool
+	InPlace            bool
+	Frequency          bool
+	Tar                bool
+	ContextHeader      string
+	Before             int
+	After              int
+	MaxFilesPerDir     int
+	Pager              bool
+	Whole              bool
+	MaxSize            int64
+	ReplaceOut         bool
+	PatternFile        string
+	PatternCount       int
+	MinLen             int
+	MaxLen             int
+	PermMask           os.FileMode
+	OwnerUID           int
+	KeyPath            bool
+	DedupInode         bool
+	Deadline           time.Duration
+	First              bool
+	Last               bool
+	Nth                int
+	HexPattern         []byte
+	SkipExtensions     map[string]bool
+	MatchIDs           bool
+	Expr               string
+	Tree               bool
+	Watch              bool
+	Histogram          string
+	Bucket             time.Duration
+	Readers            int
+	Fuzzy              int
+	Gzip               bool
+	Snippet            int
+	PruneTo            []string
+	Blame              bool
+	Checkpoint         string
+	Resume             bool
+	ReportMissing      bool
+}
+
+// sequentialBufferSize 顺序模式下读取文件使用的缓冲区大小，减少小文件的系统调用次数
+const sequentialBufferSize = 1 << 20
+
+// 文件名显示模式，对应 -H / -h 参数
+const (
+	fileNameAuto   = iota // 搜索多个文件时显示文件名
+	fileNameAlways        // -H：始终显示文件名
+	fileNameNever         // -h：从不显示文件名
+)
+
+// Summary 汇总搜索过程中收集、需在结束时统一输出的信息
+type Summary struct {
+	mu         sync.Mutex
+	MixedEOL   []string
+	Extensions map[string]int
+	Undecoded  []string
+	Unmatched  []string
+	Dirs       map[string]int
+	Tokens     map[string]int
+	Histogram  map[time.Time]int
+	Unparsed   int
+}
+
+// Searcher 汇总一次搜索所需的配置、匹配器、替换器及结果汇总
+type Searcher struct {
+	Config     *Config
+	FileRegex  *regexp2.Regexp
+	PathRegex  *regexp2.Regexp
+	Matcher    func(string) bool
+	Locator    func(string) (int, int)
+	Tokenizer  func(string) []string
+	Header     func(string) bool
+	Replacer   func(string) string
+	Near       *NearMatcher
+	Summary    *Summary
+	Printer    *Printer
+	Limiter    *adaptiveLimiter
+	Checkpoint *checkpoint
+	Ctx        context.Context
+	Cancel     context.CancelFunc
+	found      atomic.Bool
+
+	// 顺序模式下复用的读缓冲区，此时只有遍历所在的 goroutine 搜索文件
+	sequentialReader *bufio.Reader
+
+	// -dedup-inode 时记录已搜索过的文件
+	seenMu sync.Mutex
+	seen   map[fileKey]bool
+}
+
+// fileKey 以设备号和 inode 唯一标识一个文件
+type fileKey struct {
+	dev, ino uint64
+}
+
+// stopped 判断搜索是否已被取消
+func (s *Searcher) stopped() bool {
+	select {
+	case <-s.Ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// firstVisit 判断文件是否第一次被搜索，无法获取 inode 时视为第一次
+func (s *Searcher) firstVisit(info os.FileInfo) bool {
+	key, ok := fileID(info)
+	if !ok {
+		return true
+	}
+	s.seenMu.Lock()
+	defer s.seenMu.Unlock()
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	return true
+}
+
+// claimMatch 在 -stop-on-first 下仅允许第一个匹配输出，并取消其余的搜索
+func (s *Searcher) claimMatch() bool {
+	if !s.Config.StopOnFirst {
+		return true
+	}
+	if !s.found.CompareAndSwap(false, true) {
+		return false
+	}
+	s.Cancel()
+	return true
 }
 
 func main() {
 	// 解析并校验配置
 	config := parseAndValidateFlags()
+	if config.Pager {
+		defer startPager()()
+	}
+	search(config)
+	if config.Watch {
+		watchAndSearch(config)
+	}
+}
 
-	// 打印搜索信息
-	printConfig(config)
+// search 创建匹配器执行一次完整的搜索并打印汇总，-watch 时每次文件变化都会重新调用
+func search(config *Config) {
+	// 打印搜索信息，-rout 的输出是完整的文件内容，不打印
+	if !config.ReplaceOut {
+		printConfig(config)
+	}
 
-	// 创建匹配器
-	matcher := createMatcher(config)
+	// 创建匹配器与替换器
+	searcher := &Searcher{
+		Config:    config,
+		FileRegex: regexp2.MustCompile(config.FilePattern, regexp2.None),
+		Matcher:   createMatcher(config),
+		Locator:   createLocator(config),
+		Tokenizer: createTokenizer(config),
+		Header:    createHeaderMatcher(config),
+		Replacer:  createReplacer(config),
+		Near:      createNearMatcher(config),
+		Summary:   &Summary{Extensions: make(map[string]int), Dirs: make(map[string]int), Tokens: make(map[string]int), Histogram: make(map[time.Time]int)},
+		Printer:   newPrinter(config),
+	}
+	if config.DedupInode {
+		searcher.seen = make(map[fileKey]bool)
+	}
+	if config.PathPattern != "" {
+		searcher.PathRegex = regexp2.MustCompile(config.PathPattern, regexp2.None)
+	}
+	// -deadline 到期时与 -stop-on-first 一样取消搜索：遍历停止，进行中的文件在读取下一行前退出
+	if config.Deadline > 0 {
+		searcher.Ctx, searcher.Cancel = context.WithTimeout(context.Background(), config.Deadline)
+	} else {
+		searcher.Ctx, searcher.Cancel = context.WithCancel(context.Background())
+	}
+	defer searcher.Cancel()
+	if config.AutoParallelism {
+		searcher.Limiter = newAdaptiveLimiter(runtime.NumCPU(), config.Parallelism)
+	}
+	if config.Checkpoint != "" {
+		checkpoint, err := openCheckpoint(config.Checkpoint, config.Resume, searcher.stopped)
+		if err != nil {
+			log.Fatalf("Error: cannot open checkpoint %s: %v\n", config.Checkpoint, err)
+		}
+		searcher.Checkpoint = checkpoint
+		defer checkpoint.closeFile()
+	}
 
 	// 执行文件搜索
-	walkDirectory(config, matcher)
+	if config.Diff {
+		searchDiff(searcher)
+	} else {
+		walkDirectory(searcher)
+	}
+
+	if searcher.Ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Deadline of %s reached, results are incomplete\n", config.Deadline)
+	}
+	if config.Tree {
+		searcher.Printer.printTree()
+	}
+
+	// 打印汇总信息
+	printSummary(config, searcher.Summary)
 }
 
 // parseAndValidateFlags 解析命令行参数并校验
@@ -43,19 +270,278 @@ func parseAndValidateFlags() *Config {
 	filePattern := flag.String("f", "prod.yml$", "The file pattern to search for (regex)")
 	searchPattern := flag.String("s", "", "The string pattern to search within files (mutually exclusive with -ss)")
 	searchRegexPattern := flag.String("ss", "", "The regex pattern to search within files (mutually exclusive with -s)")
-	exclusionPath := flag.String("e", "target", "Directory path to exclude from search")
+	patternFile := flag.String("pf", "", "File of patterns, one per line; lines matching any of them are reported (literal unless -ssf)")
+	patternRegex := flag.Bool("ssf", false, "Treat the patterns in -pf as regexes")
+	reportMissing := flag.Bool("report-missing", false, "After the matches, list the searched files that had no match under \"No matches in:\"")
+	checkpointPath := flag.String("checkpoint", "", "Append each directory to this file once it has been fully searched, for use with -resume")
+	resume := flag.Bool("resume", false, "With -checkpoint, skip the directories recorded in the checkpoint file by an interrupted run")
+	pruneTo := flag.String("prune-to", "", "Comma-separated directory names; only descend into directories with these names below the search path, e.g. src,lib")
+	exclusionPath := flag.String("e", defaultExclusion(), "Comma-separated directory paths to exclude from search (default from $FS_EXCLUDE)")
+	pathPattern := flag.String("fp", "", "Regex matched against the slash-separated path relative to the search path, instead of -f on the file name")
 	module := flag.Int("m", 0, "Override file pattern")
-	parallelism := flag.Int("P", runtime.NumCPU()*10, "10*Number of parallel workers")
+	parallelism := flag.String("P", strconv.Itoa(runtime.NumCPU()*10), "10*Number of parallel workers, or \"auto\" to adapt to I/O load; output order is only deterministic with -P 1 or -mode ordered")
+	checkEOL := flag.Bool("crlf", false, "Report files with mixed CRLF and LF line endings")
+	jsonSummary := flag.Bool("jsonsummary", false, "Print match counts grouped by file extension as JSON")
+	withFileName := flag.Bool("H", false, "Always print the file name for each match")
+	noFileName := flag.Bool("h", false, "Never print the file name for each match")
+	replacement := flag.String("r", "", "Print matched lines with the match replaced (supports $1/${name} with -ss)")
+	replaceOut := flag.Bool("rout", false, "With -r, print every line of each matching file with replacements applied, without modifying it")
+	inPlace := flag.Bool("w", false, "With -r, write replacements back to the files (atomically, via a temp file and rename)")
+	tarArchive := flag.Bool("tar", false, "Also search text entries matching -f inside .tar.gz/.tgz archives")
+	gzipFiles := flag.Bool("gz", false, "Also search .gz files whose name without .gz matches -f, decompressing them transparently")
+	archive := flag.Bool("archive", false, "Also search text entries matching -f inside .zip/.jar archives")
+	encodingList := flag.String("encoding", "", "Comma-separated encodings to try in order, e.g. utf-8,utf-16,latin1 (a BOM takes precedence)")
+	statsByDir := flag.Bool("statsdir", false, "Print match counts grouped by top-level directory under the search path")
+	tracked := flag.Bool("tracked", false, "Only search files tracked by git (falls back to a normal walk outside a git repo)")
+	diff := flag.Bool("diff", false, "Only search lines added in the uncommitted changes of the git repo (git diff HEAD)")
+	vimGrep := flag.Bool("vimgrep", false, "Print matches as path:line:col:text (col is the 1-based byte column of the first match)")
+	nullData := flag.Bool("z", false, "Treat input as NUL-separated records instead of lines")
+	mode := flag.String("mode", "parallel", "Search mode: parallel (one goroutine per file), sequential (single reader with a large buffer) or ordered (parallel, but output in walk order; a slow file holds back the output of at most -P files after it)")
+	notPattern := flag.String("snot", "", "Exclude lines that also contain (with -s) or match (with -ss) this pattern")
+	separate := flag.Bool("sep", false, "Print a separator line when results switch to a different file")
+	separator := flag.String("sepstr", "--", "Separator line printed by -sep")
+	stopOnFirst := flag.Bool("stop-on-first", false, "Stop the whole search as soon as the first match is found")
+	format := flag.String("format", "", "Go text/template for each match, e.g. '{{.Path}}:{{.Line}}:{{.Col}}:{{.Text}}'")
+	rangesFile := flag.String("ranges", "", "File of path:start-end lines; only search those line ranges of those files")
+	trim := flag.Bool("trim", false, "Strip leading and trailing whitespace from printed lines (matching uses the original line)")
+	frequency := flag.Bool("freq", false, "Instead of printing lines, count every matched substring and print them by frequency")
+	contextHeader := flag.String("context-header", "", "Regex for section headers (e.g. '^func '); print the nearest preceding header above each match")
+	after := flag.Int("A", 0, "Print N lines of context after each match")
+	before := flag.Int("B", 0, "Print N lines of context before each match")
+	aroundLines := flag.Int("C", 0, "Print N lines of context around each match (-A and -B take precedence)")
+	maxFilesPerDir := flag.Int("maxfiles-per-dir", 0, "Search at most N matching files per directory and skip the rest with a warning (0 means no limit)")
+	pager := flag.Bool("pager", false, "Page the output through $PAGER (default \"less -R\") when writing to a terminal")
+	whole := flag.Bool("whole", false, "Match the pattern once against the whole file content and print the paths of matching files")
+	maxSize := flag.Int64("maxsize", 0, "Skip files larger than N bytes (0 means no limit)")
+	minLen := flag.Int("minlen", 0, "Only report matching lines with at least N characters")
+	maxLen := flag.Int("maxlen", 0, "Only report matching lines with at most N characters (0 means no limit)")
+	perm := flag.String("perm", "", "Only search files whose permission bits include all bits of this octal mask, e.g. 002 for world-writable")
+	owner := flag.String("owner", "", "Only search files owned by this user name or uid (Unix only)")
+	blame := flag.Bool("blame", false, "Annotate each matching line with the author and short SHA of the commit that last changed it (git blame)")
+	keyPath := flag.Bool("keypath", false, "For YAML-like files, print the dotted key path (e.g. spring.datasource.url) of each matching line")
+	dedupInode := flag.Bool("dedup-inode", false, "Search each file only once when it is reachable through hard links or symlinks (Unix only)")
+	deadline := flag.Duration("deadline", 0, "Stop searching after this long, e.g. 30s, and print what was found so far (0 means no limit)")
+	first := flag.Bool("first", false, "Print only the first matching line of each file")
+	last := flag.Bool("last", false, "Print only the last matching line of each file")
+	nth := flag.Int("nth", 0, "Print only the Nth matching line of each file (1-indexed)")
+	hexMode := flag.Bool("hex", false, "Treat -s as a hex byte sequence (e.g. EFBBBF) and report the byte offsets where it occurs")
+	skipExt := flag.String("skipext", "", "Comma-separated file extensions to skip before any other check, e.g. .png,.jpg,.pdf")
+	snippetWidth := flag.Int("snippet", 0, "Print only the match and up to N characters on each side of it within the line, with … marking truncated ends")
+	fuzzy := flag.Int("fuzzy", 0, "Match lines containing a substring within Levenshtein distance N of -s (much slower than an exact -s search)")
+	readers := flag.Int("readers", 0, "Open and read at most N files at a time, handing their content to the -P search workers (0 means each worker reads its own file)")
+	histogram := flag.String("histogram", "", "Instead of printing matches, bucket them by the timestamp at the start of each line, parsed with this Go time layout (e.g. \"2006-01-02 15:04:05\"), and print a histogram")
+	bucket := flag.Duration("bucket", time.Hour, "Interval of each -histogram bucket")
+	watch := flag.Bool("watch", false, "After the search, keep watching the search path and re-run the search whenever files matching -f change")
+	tree := flag.Bool("tree", false, "Collect all results and print them grouped under a directory tree once the search finishes")
+	expr := flag.String("expr", "", "Match lines by a boolean expression instead of -s/-ss, e.g. \"contains('A') && !contains('B') || regex('C\\d+')\" (supports &&, ||, ! and parentheses)")
+	matchIDs := flag.Bool("id", false, "Print a unique sequential ID as the first column of each match (available as .ID in -format)")
+	nonBlank := flag.Bool("nonblank", false, "Skip empty or whitespace-only lines even if they match")
+	near := flag.String("near", "", "Report lines where two regexes match within N lines of each other (format: patternA|patternB:N)")
 
+	applyRCFile()
 	flag.Parse()
 
 	// 参数校验
-	if *searchPattern == "" && *searchRegexPattern == "" {
-		log.Fatalf("Error: You must provide either -s or -ss argument.\n")
+	if *searchPattern == "" && *searchRegexPattern == "" && *near == "" && *patternFile == "" && *expr == "" {
+		log.Fatalf("Error: You must provide either -s, -ss, -pf, -near or -expr argument.\n")
+	}
+	if *expr != "" {
+		if *searchPattern != "" || *searchRegexPattern != "" || *patternFile != "" || *near != "" || *notPattern != "" || *replacement != "" || *frequency {
+			log.Fatalf("Error: -expr cannot be used with -s, -ss, -pf, -near, -snot, -r or -freq.\n")
+		}
+		if _, err := parseExpr(*expr); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+	if *patternFile != "" && (*searchPattern != "" || *searchRegexPattern != "" || *near != "") {
+		log.Fatalf("Error: -pf is mutually exclusive with -s, -ss and -near.\n")
+	}
+	if *patternRegex && *patternFile == "" {
+		log.Fatalf("Error: -ssf requires -pf.\n")
 	}
 	if *searchPattern != "" && *searchRegexPattern != "" {
 		log.Fatalf("Error: -s and -ss are mutually exclusive.\n")
 	}
+	if *near != "" && (*searchPattern != "" || *searchRegexPattern != "") {
+		log.Fatalf("Error: -near is mutually exclusive with -s and -ss.\n")
+	}
+	if *near != "" && (*diff || *vimGrep) {
+		log.Fatalf("Error: -near cannot be used with -diff or -vimgrep.\n")
+	}
+	if *frequency && (*near != "" || *inPlace || *replaceOut) {
+		log.Fatalf("Error: -freq cannot be used with -near, -w or -rout.\n")
+	}
+	if *contextHeader != "" && (*near != "" || *frequency || *inPlace || *replaceOut || *diff) {
+		log.Fatalf("Error: -context-header cannot be used with -near, -freq, -w, -rout or -diff.\n")
+	}
+	if *maxFilesPerDir < 0 {
+		log.Fatalf("Error: -maxfiles-per-dir must not be negative.\n")
+	}
+	if *after < 0 || *before < 0 || *aroundLines < 0 {
+		log.Fatalf("Error: -A, -B and -C must not be negative.\n")
+	}
+	if *after == 0 {
+		*after = *aroundLines
+	}
+	if *before == 0 {
+		*before = *aroundLines
+	}
+	if (*after > 0 || *before > 0) && (*near != "" || *frequency || *inPlace || *replaceOut || *diff) {
+		log.Fatalf("Error: -A, -B and -C cannot be used with -near, -freq, -w, -rout or -diff.\n")
+	}
+	if *whole && (*near != "" || *frequency || *replacement != "" || *diff || *vimGrep || *after > 0 || *before > 0 || *contextHeader != "") {
+		log.Fatalf("Error: -whole cannot be used with -near, -freq, -r, -diff, -vimgrep, -A/-B/-C or -context-header.\n")
+	}
+	if *minLen < 0 || *maxLen < 0 || (*maxLen > 0 && *minLen > *maxLen) {
+		log.Fatalf("Error: -minlen and -maxlen must not be negative and -minlen must not exceed -maxlen.\n")
+	}
+	if *keyPath && (*near != "" || *frequency || *inPlace || *replaceOut || *diff || *whole) {
+		log.Fatalf("Error: -keypath cannot be used with -near, -freq, -w, -rout, -diff or -whole.\n")
+	}
+	if *fuzzy < 0 {
+		log.Fatalf("Error: -fuzzy must not be negative.\n")
+	}
+	if *fuzzy > 0 && (*searchPattern == "" || *replacement != "" || *frequency || *hexMode) {
+		log.Fatalf("Error: -fuzzy requires -s and cannot be used with -r, -freq or -hex.\n")
+	}
+	if *blame && (*after > 0 || *before > 0 || *diff || *whole || *frequency || *inPlace || *replaceOut || *near != "") {
+		log.Fatalf("Error: -blame cannot be used with -A/-B/-C, -diff, -whole, -freq, -w, -rout or -near.\n")
+	}
+	if *reportMissing && *diff {
+		log.Fatalf("Error: -report-missing cannot be used with -diff.\n")
+	}
+	if *resume && *checkpointPath == "" {
+		log.Fatalf("Error: -resume requires -checkpoint.\n")
+	}
+	if *checkpointPath != "" && (*tracked || *diff || *watch) {
+		log.Fatalf("Error: -checkpoint cannot be used with -tracked, -diff or -watch.\n")
+	}
+	if *snippetWidth < 0 {
+		log.Fatalf("Error: -snippet must not be negative.\n")
+	}
+	if *snippetWidth > 0 && *replacement != "" {
+		log.Fatalf("Error: -snippet cannot be used with -r.\n")
+	}
+	if *readers < 0 {
+		log.Fatalf("Error: -readers must not be negative.\n")
+	}
+	if *readers > 0 && (*mode != "parallel" || *parallelism == "auto") {
+		log.Fatalf("Error: -readers cannot be used with -mode sequential, -mode ordered or -P auto.\n")
+	}
+	if *histogram != "" && (*near != "" || *frequency || *inPlace || *replaceOut || *whole || *hexMode) {
+		log.Fatalf("Error: -histogram cannot be used with -near, -freq, -w, -rout, -whole or -hex.\n")
+	}
+	if *bucket <= 0 {
+		log.Fatalf("Error: -bucket must be positive.\n")
+	}
+	if *watch && (*pager || *inPlace || *replaceOut || *diff) {
+		log.Fatalf("Error: -watch cannot be used with -pager, -w, -rout or -diff.\n")
+	}
+	if *tree && (*format != "" || *vimGrep || *replaceOut || *matchIDs || *after > 0 || *before > 0) {
+		log.Fatalf("Error: -tree cannot be used with -format, -vimgrep, -rout, -id or -A/-B/-C.\n")
+	}
+	if *matchIDs && (*vimGrep || *replaceOut) {
+		log.Fatalf("Error: -id cannot be used with -vimgrep or -rout.\n")
+	}
+	if *nth < 0 {
+		log.Fatalf("Error: -nth must not be negative.\n")
+	}
+	if (*first && *last) || (*nth > 0 && (*first || *last)) {
+		log.Fatalf("Error: -first, -last and -nth are mutually exclusive.\n")
+	}
+	if (*first || *last || *nth > 0) && (*near != "" || *frequency || *inPlace || *replaceOut || *whole || *after > 0 || *before > 0) {
+		log.Fatalf("Error: -first, -last and -nth cannot be used with -near, -freq, -w, -rout, -whole or -A/-B/-C.\n")
+	}
+	if (*last || *nth > 0) && *stopOnFirst {
+		log.Fatalf("Error: -last and -nth cannot be used with -stop-on-first.\n")
+	}
+	if *deadline < 0 {
+		log.Fatalf("Error: -deadline must not be negative.\n")
+	}
+	if *maxSize < 0 {
+		log.Fatalf("Error: -maxsize must not be negative.\n")
+	}
+	if *inPlace && (*replacement == "" || *near != "" || *encodingList != "" || *nullData || *diff) {
+		log.Fatalf("Error: -w requires -r and cannot be used with -near, -encoding, -z or -diff.\n")
+	}
+	if *replaceOut && (*replacement == "" || *inPlace || *near != "" || *encodingList != "" || *nullData || *diff) {
+		log.Fatalf("Error: -rout requires -r and cannot be used with -w, -near, -encoding, -z or -diff.\n")
+	}
+	// -pf 的模式合并为一个正则，之后与 -ss 的处理方式相同
+	patternCount := 0
+	if *patternFile != "" {
+		var err error
+		if *searchRegexPattern, patternCount, err = loadPatterns(*patternFile, *patternRegex); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+
+	nearPatterns, nearDistance, err := parseNear(*near)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+
+	encodings, err := parseEncodings(*encodingList)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+
+	if *mode != "parallel" && *mode != "sequential" && *mode != "ordered" {
+		log.Fatalf("Error: -mode must be parallel, sequential or ordered.\n")
+	}
+	if *mode == "sequential" && *parallelism == "auto" {
+		log.Fatalf("Error: -P auto cannot be used with -mode sequential.\n")
+	}
+
+	var ranges map[string][]lineRange
+	if *rangesFile != "" {
+		if ranges, err = loadRanges(*rangesFile); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+
+	var permMask uint64
+	if *perm != "" {
+		if permMask, err = strconv.ParseUint(*perm, 8, 32); err != nil || permMask > 0o777 {
+			log.Fatalf("Error: -perm must be an octal permission mask such as 002.\n")
+		}
+	}
+	ownerUID := -1
+	if *owner != "" {
+		if ownerUID, err = lookupOwner(*owner); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+
+	var hexPattern []byte
+	if *hexMode {
+		if *searchPattern == "" || *replacement != "" || *frequency || *whole || *diff || *encodingList != "" ||
+			*after > 0 || *before > 0 || *keyPath || *contextHeader != "" || *first || *last || *nth > 0 {
+			log.Fatalf("Error: -hex requires -s and cannot be used with -r, -freq, -whole, -diff, -encoding, -A/-B/-C, -keypath, -context-header, -first, -last or -nth.\n")
+		}
+		if hexPattern, err = parseHexPattern(*searchPattern); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+
+	// -P auto 时以 10*CPU 数作为并发上限
+	autoParallelism := *parallelism == "auto"
+	workers := runtime.NumCPU() * 10
+	if !autoParallelism {
+		if workers, err = strconv.Atoi(*parallelism); err != nil || workers < 1 {
+			log.Fatalf("Error: -P must be a positive integer or \"auto\".\n")
+		}
+	}
+	if *withFileName && *noFileName {
+		log.Fatalf("Error: -H and -h are mutually exclusive.\n")
+	}
+
+	fileNameMode := fileNameAuto
+	if *withFileName {
+		fileNameMode = fileNameAlways
+	} else if *noFileName {
+		fileNameMode = fileNameNever
+	}
 
 	searchPath := "."
 	if len(flag.Args()) > 0 {
@@ -67,13 +553,235 @@ func parseAndValidateFlags() *Config {
 
 	return &Config{
 		FilePattern:        setFilePattern(*filePattern, *module),
+		PathPattern:        *pathPattern,
 		SearchPattern:      *searchPattern,
 		SearchRegexPattern: *searchRegexPattern,
-		ExclusionPath:      filepath.FromSlash(*exclusionPath),
+		ExclusionPaths:     splitExclusions(*exclusionPath),
 		Module:             *module,
-		Parallelism:        *parallelism,
+		Parallelism:        workers,
+		AutoParallelism:    autoParallelism,
 		SearchPath:         filepath.FromSlash(searchPath),
+		CheckEOL:           *checkEOL,
+		JSONSummary:        *jsonSummary,
+		FileNameMode:       fileNameMode,
+		Replacement:        *replacement,
+		NearPatterns:       nearPatterns,
+		NearDistance:       nearDistance,
+		Archive:            *archive,
+		NonBlank:           *nonBlank,
+		Encodings:          encodings,
+		StatsByDir:         *statsByDir,
+		Tracked:            *tracked,
+		Diff:               *diff,
+		VimGrep:            *vimGrep,
+		NullData:           *nullData,
+		Sequential:         *mode == "sequential",
+		Ordered:            *mode == "ordered",
+		NotPattern:         *notPattern,
+		Separate:           *separate,
+		Separator:          *separator,
+		StopOnFirst:        *stopOnFirst,
+		Format:             *format,
+		Ranges:             ranges,
+		Trim:               *trim,
+		InPlace:            *inPlace,
+		ReplaceOut:         *replaceOut,
+		PatternFile:        *patternFile,
+		PatternCount:       patternCount,
+		MinLen:             *minLen,
+		MaxLen:             *maxLen,
+		PermMask:           os.FileMode(permMask),
+		OwnerUID:           ownerUID,
+		KeyPath:            *keyPath,
+		DedupInode:         *dedupInode,
+		Deadline:           *deadline,
+		First:              *first,
+		Last:               *last,
+		Nth:                *nth,
+		MatchIDs:           *matchIDs,
+		Expr:               *expr,
+		Tree:               *tree,
+		Watch:              *watch,
+		Histogram:          *histogram,
+		Bucket:             *bucket,
+		Readers:            *readers,
+		Fuzzy:              *fuzzy,
+		Gzip:               *gzipFiles,
+		Snippet:            *snippetWidth,
+		PruneTo:            splitExclusions(*pruneTo),
+		Blame:              *blame,
+		Checkpoint:         *checkpointPath,
+		Resume:             *resume,
+		ReportMissing:      *reportMissing,
+		HexPattern:         hexPattern,
+		SkipExtensions:     splitExtensions(*skipExt),
+		Frequency:          *frequency,
+		Tar:                *tarArchive,
+		ContextHeader:      *contextHeader,
+		Before:             *before,
+		After:              *after,
+		MaxFilesPerDir:     *maxFilesPerDir,
+		Pager:              *pager,
+		Whole:              *whole,
+		MaxSize:            *maxSize,
+	}
+}
+
+// defaultExclusion 返回 -e 的默认值，环境变量 FS_EXCLUDE 优先于内置默认值
+func defaultExclusion() string {
+	if exclusion := os.Getenv("FS_EXCLUDE"); exclusion != "" {
+		return exclusion
+	}
+	return "target"
+}
+
+// rcKeys 将 ~/.fsrc 中允许的配置项映射到命令行参数名；只列出影响搜索范围与输出格式的参数，
+// 会修改文件（-w）或改变运行方式（-watch、-checkpoint 等）的参数不能通过配置文件默认开启
+var rcKeys = map[string]string{
+	"exclude":     "e",
+	"parallelism": "P",
+	"mode":        "mode",
+	"maxsize":     "maxsize",
+	"skipext":     "skipext",
+	"encoding":    "encoding",
+	"sep":         "sep",
+	"sepstr":      "sepstr",
+	"trim":        "trim",
+	"pager":       "pager",
+}
+
+// rcUnsupported 列出 ~/.fsrc 中识别但 fs 尚无对应功能的配置项及原因
+var rcUnsupported = map[string]string{
+	"color":  "fs does not colorize its output",
+	"hidden": "fs always searches hidden files and directories",
+}
+
+// applyRCFile 读取 ~/.fsrc（每行 key=value）作为参数默认值，须在 flag.Parse 之前调用，
+// 以保证显式指定的命令行参数优先；FS_EXCLUDE 环境变量优先于文件中的 exclude
+func applyRCFile() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	rcPath := filepath.Join(home, ".fsrc")
+	file, err := os.Open(rcPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error opening %s: %v\n", rcPath, err)
+		}
+		return
 	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			log.Printf("Warning: %s:%d: expected key=value\n", rcPath, lineNo)
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if reason, exists := rcUnsupported[key]; exists {
+			log.Printf("Warning: %s:%d: key %q is not supported: %s\n", rcPath, lineNo, key, reason)
+			continue
+		}
+		name, exists := rcKeys[key]
+		if !exists {
+			log.Printf("Warning: %s:%d: unsupported key %q\n", rcPath, lineNo, key)
+			continue
+		}
+		if name == "e" && os.Getenv("FS_EXCLUDE") != "" {
+			continue
+		}
+		if err := flag.Lookup(name).Value.Set(value); err != nil {
+			log.Printf("Warning: %s:%d: invalid value for %s: %v\n", rcPath, lineNo, key, err)
+		}
+	}
+}
+
+// splitExclusions 将逗号分隔的排除路径拆分为列表
+func splitExclusions(exclusion string) []string {
+	var paths []string
+	for _, path := range strings.Split(exclusion, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, filepath.FromSlash(path))
+		}
+	}
+	return paths
+}
+
+// splitExtensions 解析 -skipext，统一为小写并补齐前导点，未指定时返回 nil
+func splitExtensions(list string) map[string]bool {
+	var extensions map[string]bool
+	for _, ext := range strings.Split(list, ",") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if extensions == nil {
+			extensions = make(map[string]bool)
+		}
+		extensions[ext] = true
+	}
+	return extensions
+}
+
+// isExcluded 判断路径是否包含任一排除路径
+func isExcluded(path string, exclusions []string) bool {
+	for _, exclusion := range exclusions {
+		if strings.Contains(path, exclusion) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPruned 判断 -prune-to 时目录是否应被跳过：搜索根目录及路径中含有任一指定目录名的目录不跳过
+func isPruned(config *Config, dir string) bool {
+	if config.PruneTo == nil {
+		return false
+	}
+	rel, err := filepath.Rel(config.SearchPath, dir)
+	if err != nil || rel == "." {
+		return false
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		for _, allowed := range config.PruneTo {
+			if name == allowed {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseNear 解析 -near 参数，格式为 patternA|patternB:N
+func parseNear(near string) ([]string, int, error) {
+	if near == "" {
+		return nil, 0, nil
+	}
+
+	sep := strings.LastIndex(near, ":")
+	if sep < 0 {
+		return nil, 0, fmt.Errorf("-near must be in the form patternA|patternB:N")
+	}
+	distance, err := strconv.Atoi(near[sep+1:])
+	if err != nil || distance < 0 {
+		return nil, 0, fmt.Errorf("-near distance must be a non-negative integer: %s", near[sep+1:])
+	}
+
+	patterns := strings.SplitN(near[:sep], "|", 2)
+	if len(patterns) != 2 || patterns[0] == "" || patterns[1] == "" {
+		return nil, 0, fmt.Errorf("-near must contain two patterns separated by '|'")
+	}
+	return patterns, distance, nil
 }
 
 // setFilePattern 根据 -m 参数设置文件匹配模式
@@ -97,13 +805,67 @@ func setFilePattern(filePattern string, module int) string {
 
 // createMatcher 创建搜索匹配器
 func createMatcher(config *Config) func(string) bool {
-	if config.SearchPattern != "" {
+	if len(config.NearPatterns) > 0 {
+		return nil
+	}
+
+	var matcher func(string) bool
+	if config.Expr != "" {
+		// 表达式已在参数校验时检查过
+		matcher, _ = parseExpr(config.Expr)
+	} else if config.Fuzzy > 0 {
+		pattern := []rune(config.SearchPattern)
+		matcher = func(line string) bool {
+			return fuzzyContains(line, pattern, config.Fuzzy)
+		}
+	} else {
+		matcher = createPatternMatcher(config.SearchPattern, config.SearchRegexPattern)
+	}
+	if config.NotPattern != "" {
+		// -snot 与主模式类型一致：-s 时按字面量排除，-ss 时按正则排除
+		var notMatcher func(string) bool
+		if config.SearchPattern != "" {
+			notMatcher = createPatternMatcher(config.NotPattern, "")
+		} else {
+			notMatcher = createPatternMatcher("", config.NotPattern)
+		}
+		patternMatcher := matcher
+		matcher = func(line string) bool {
+			return patternMatcher(line) && !notMatcher(line)
+		}
+	}
+
+	// -minlen / -maxlen 按字符数限制匹配行的长度，先判断长度以跳过不必要的正则匹配
+	if config.MinLen > 0 || config.MaxLen > 0 {
+		patternMatcher := matcher
+		matcher = func(line string) bool {
+			length := utf8.RuneCountInString(line)
+			if length < config.MinLen || (config.MaxLen > 0 && length > config.MaxLen) {
+				return false
+			}
+			return patternMatcher(line)
+		}
+	}
+	return matcher
+}
+
+// createHeaderMatcher 创建 -context-header 的匹配函数，未指定时返回 nil
+func createHeaderMatcher(config *Config) func(string) bool {
+	if config.ContextHeader == "" {
+		return nil
+	}
+	return createPatternMatcher("", config.ContextHeader)
+}
+
+// createPatternMatcher 创建单个模式的匹配函数，literal 非空时按字面量匹配，否则按正则匹配
+func createPatternMatcher(literal, pattern string) func(string) bool {
+	if literal != "" {
 		return func(line string) bool {
-			return strings.Contains(line, config.SearchPattern)
+			return strings.Contains(line, literal)
 		}
 	}
 
-	regex := regexp2.MustCompile(config.SearchRegexPattern, regexp2.None)
+	regex := regexp2.MustCompile(pattern, regexp2.None)
 	return func(line string) bool {
 		if match, err := regex.MatchString(line); err == nil {
 			return match
@@ -112,77 +874,818 @@ func createMatcher(config *Config) func(string) bool {
 	}
 }
 
+// createLocator 创建定位器，返回行内第一个匹配的字节起止位置，未匹配时返回 -1, -1
+func createLocator(config *Config) func(string) (int, int) {
+	if len(config.NearPatterns) > 0 {
+		return nil
+	}
+	// -expr 可能由多个子模式组成，不定位匹配位置，列号输出为 0
+	if config.Expr != "" {
+		return func(string) (int, int) { return -1, -1 }
+	}
+	if config.SearchPattern != "" {
+		return func(line string) (int, int) {
+			start := strings.Index(line, config.SearchPattern)
+			if start < 0 {
+				return -1, -1
+			}
+			return start, start + len(config.SearchPattern)
+		}
+	}
+
+	regex := regexp2.MustCompile(config.SearchRegexPattern, regexp2.None)
+	return func(line string) (int, int) {
+		match, err := regex.FindStringMatch(line)
+		if err != nil || match == nil {
+			return -1, -1
+		}
+		// regexp2 返回的是字符（rune）位置，需转换为字节位置
+		runes := []rune(line)
+		start := len(string(runes[:match.Index]))
+		return start, start + len(string(runes[match.Index:match.Index+match.Length]))
+	}
+}
+
+// createTokenizer 创建提取器，返回行内所有不重叠的匹配子串，仅在 -freq 时创建
+func createTokenizer(config *Config) func(string) []string {
+	if !config.Frequency {
+		return nil
+	}
+	if config.SearchPattern != "" {
+		return func(line string) []string {
+			tokens := make([]string, strings.Count(line, config.SearchPattern))
+			for i := range tokens {
+				tokens[i] = config.SearchPattern
+			}
+			return tokens
+		}
+	}
+
+	regex := regexp2.MustCompile(config.SearchRegexPattern, regexp2.None)
+	return func(line string) []string {
+		var tokens []string
+		match, err := regex.FindStringMatch(line)
+		for err == nil && match != nil {
+			tokens = append(tokens, match.String())
+			match, err = regex.FindNextMatch(match)
+		}
+		return tokens
+	}
+}
+
+// createReplacer 创建替换器，未指定 -r 时返回 nil
+func createReplacer(config *Config) func(string) string {
+	if config.Replacement == "" {
+		return nil
+	}
+
+	if config.SearchPattern != "" {
+		return func(line string) string {
+			return strings.ReplaceAll(line, config.SearchPattern, config.Replacement)
+		}
+	}
+
+	regex := regexp2.MustCompile(config.SearchRegexPattern, regexp2.None)
+	if err := validateReplacement(regex, config.Replacement); err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	return func(line string) string {
+		if replaced, err := regex.Replace(line, config.Replacement, -1, -1); err == nil {
+			return replaced
+		}
+		return line
+	}
+}
+
+// validateReplacement 校验替换串中引用的分组（$1、${name}）均存在于正则中
+func validateReplacement(regex *regexp2.Regexp, replacement string) error {
+	groups := make(map[string]bool)
+	for _, name := range regex.GetGroupNames() {
+		groups[name] = true
+	}
+
+	for i := 0; i < len(replacement)-1; i++ {
+		if replacement[i] != '$' {
+			continue
+		}
+		rest := replacement[i+1:]
+		if rest[0] == '$' {
+			i++
+			continue
+		}
+
+		var ref string
+		if rest[0] == '{' {
+			if end := strings.IndexByte(rest, '}'); end > 0 {
+				ref = rest[1:end]
+			}
+		} else {
+			for _, c := range rest {
+				if c < '0' || c > '9' {
+					break
+				}
+				ref += string(c)
+			}
+		}
+		if ref != "" && !groups[ref] {
+			return fmt.Errorf("replacement references group %q which does not exist in the pattern", ref)
+		}
+	}
+	return nil
+}
+
 // printConfig 打印配置信息
 func printConfig(config *Config) {
 	fmt.Printf("Searching in: \t\t%s\n", config.SearchPath)
-	fmt.Printf("Max parallelism: \t%d\n", config.Parallelism)
-	fmt.Printf("Excluding: \t\t%s\n", config.ExclusionPath)
-	fmt.Printf("File pattern: \t\t%s\n", config.FilePattern)
-	if config.SearchPattern != "" {
-		fmt.Printf("Search value: \t\t%s\n\n", config.SearchPattern)
+	if config.Sequential {
+		fmt.Printf("Max parallelism: \t1 (sequential)\n")
+	} else if config.AutoParallelism {
+		fmt.Printf("Max parallelism: \tauto (%d-%d)\n", runtime.NumCPU(), config.Parallelism)
+	} else if config.Ordered {
+		fmt.Printf("Max parallelism: \t%d (ordered)\n", config.Parallelism)
 	} else {
-		fmt.Printf("Search regex: \t\t%s\n\n", config.SearchRegexPattern)
+		fmt.Printf("Max parallelism: \t%d\n", config.Parallelism)
+	}
+	fmt.Printf("Excluding: \t\t%s\n", strings.Join(config.ExclusionPaths, ", "))
+	if config.PruneTo != nil {
+		fmt.Printf("Descending into: \t%s\n", strings.Join(config.PruneTo, ", "))
+	}
+	if config.PathPattern != "" {
+		fmt.Printf("Path pattern: \t\t%s\n", config.PathPattern)
+	} else {
+		fmt.Printf("File pattern: \t\t%s\n", config.FilePattern)
+	}
+	if len(config.NearPatterns) > 0 {
+		fmt.Printf("Search near: \t\t%s | %s (within %d lines)\n", config.NearPatterns[0], config.NearPatterns[1], config.NearDistance)
+	} else if config.PatternFile != "" {
+		fmt.Printf("Search patterns: \t%d from %s\n", config.PatternCount, config.PatternFile)
+	} else if config.HexPattern != nil {
+		fmt.Printf("Search bytes: \t\t% x\n", config.HexPattern)
+	} else if config.Expr != "" {
+		fmt.Printf("Search expr: \t\t%s\n", config.Expr)
+	} else if config.SearchPattern != "" && config.Fuzzy > 0 {
+		fmt.Printf("Search value: \t\t%s (within distance %d)\n", config.SearchPattern, config.Fuzzy)
+	} else if config.SearchPattern != "" {
+		fmt.Printf("Search value: \t\t%s\n", config.SearchPattern)
+	} else {
+		fmt.Printf("Search regex: \t\t%s\n", config.SearchRegexPattern)
+	}
+	if config.NotPattern != "" {
+		fmt.Printf("Excluding lines: \t%s\n", config.NotPattern)
+	}
+	if config.ContextHeader != "" {
+		fmt.Printf("Context header: \t%s\n", config.ContextHeader)
+	}
+	if config.Replacement != "" {
+		fmt.Printf("Replace with: \t\t%s\n", config.Replacement)
 	}
+	fmt.Println()
 }
 
 // walkDirectory 遍历目录并执行文件内容搜索
-func walkDirectory(config *Config, matcher func(string) bool) {
-	regex := regexp2.MustCompile(config.FilePattern, regexp2.None)
-
+func walkDirectory(searcher *Searcher) {
+	config := searcher.Config
 	sem := make(chan struct{}, config.Parallelism)
+	acquire, release := func() { sem <- struct{}{} }, func() { <-sem }
+	if searcher.Limiter != nil {
+		acquire, release = searcher.Limiter.acquire, searcher.Limiter.release
+	}
 	var wg sync.WaitGroup
+	inline := config.Sequential || config.Parallelism == 1
+	// -mode ordered 时文件输出后才释放并发名额，见 reorderBuffer
+	if config.Ordered && !inline {
+		searcher.Printer.order = newReorderBuffer(release)
+	}
 
-	err := filepath.WalkDir(config.SearchPath, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
+	var pipeline *readPipeline
+	if config.Readers > 0 && !inline {
+		pipeline = startReadPipeline(searcher)
+	}
+
+	// 顺序模式及 -P 1 时直接在遍历中搜索，输出严格按遍历顺序，多次运行结果一致
+	dispatch := func(path string, showName bool) {
+		if inline {
+			searchInFile(path, showName, nil, searcher)
+			return
+		}
+		if pipeline != nil {
+			pipeline.dispatch(path, showName)
+			return
 		}
+		wg.Add(1)
+		acquire()
+		if searcher.Printer.order != nil {
+			seq := searcher.Printer.Begin("./" + strings.ReplaceAll(path, "\\", "/"))
+			go func() {
+				defer wg.Done()
+				searchInFile(path, showName, nil, searcher)
+				searcher.Printer.End(seq)
+			}()
+			return
+		}
+		go func() {
+			defer wg.Done()
+			searchInFile(path, showName, nil, searcher)
+			release()
+		}()
+	}
 
-		if d.IsDir() || strings.Contains(path, config.ExclusionPath) {
-			return nil
+	// 默认模式下暂存第一个文件，直到确定匹配的文件不止一个时才显示文件名
+	var pending string
+	fileCount := 0
+	// -maxfiles-per-dir 时记录每个目录已调度的文件数
+	dirFiles := make(map[string]int)
+
+	// visit 按排除路径和文件名模式过滤文件后调度搜索
+	visit := func(path string) {
+		// -skipext 只比较扩展名，先于正则匹配及打开文件
+		if config.SkipExtensions != nil && config.SkipExtensions[strings.ToLower(filepath.Ext(path))] {
+			return
+		}
+		name := filepath.Base(path)
+		if isExcluded(path, config.ExclusionPaths) || isPruned(config, filepath.Dir(path)) {
+			return
+		}
+		if config.Ranges != nil && config.Ranges[rangeKey(path)] == nil {
+			return
+		}
+		if !isSearchableArchive(config, name) && !matchFile(searcher, path) && !matchGzipFile(searcher, path) {
+			return
+		}
+		if (config.PermMask != 0 || config.OwnerUID >= 0) && !matchFileMeta(config, path) {
+			return
+		}
+		if config.MaxFilesPerDir > 0 {
+			dir := filepath.Dir(path)
+			dirFiles[dir]++
+			if dirFiles[dir] > config.MaxFilesPerDir {
+				if dirFiles[dir] == config.MaxFilesPerDir+1 {
+					log.Printf("Skipping remaining files in %s: more than %d files\n", dir, config.MaxFilesPerDir)
+				}
+				return
+			}
 		}
 
-		if isMatch, err := regex.MatchString(d.Name()); err != nil || !isMatch {
-			return nil
+		if searcher.Checkpoint != nil {
+			searcher.Checkpoint.add(path)
+		}
+		fileCount++
+		switch {
+		case config.FileNameMode != fileNameAuto:
+			dispatch(path, config.FileNameMode == fileNameAlways)
+		case fileCount == 1:
+			pending = path
+		case fileCount == 2:
+			dispatch(pending, true)
+			dispatch(path, true)
+		default:
+			dispatch(path, true)
 		}
+	}
 
-		wg.Add(1)
-		sem <- struct{}{}
-		go func(path string) {
-			defer wg.Done()
-			searchInFile(path, matcher)
-			<-sem
-		}(path)
+	// -tracked 时仅搜索 git 跟踪的文件，无法获取时回退为普通遍历
+	var err error
+	walked := false
+	if config.Tracked {
+		var files []string
+		if files, err = listTrackedFiles(config.SearchPath); err != nil {
+			log.Printf("Cannot list git tracked files, falling back to walking the path: %v\n", err)
+		} else {
+			walked = true
+			for _, path := range files {
+				if searcher.stopped() {
+					break
+				}
+				visit(path)
+			}
+		}
+	}
+	if !walked {
+		err = filepath.WalkDir(config.SearchPath, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if searcher.stopped() {
+				return filepath.SkipAll
+			}
+			if d.IsDir() && isPruned(config, path) {
+				return filepath.SkipDir
+			}
+			if searcher.Checkpoint != nil {
+				searcher.Checkpoint.walkTo(path)
+				if d.IsDir() && !searcher.Checkpoint.enter(path) {
+					return filepath.SkipDir
+				}
+			}
+			if !d.IsDir() {
+				visit(path)
+			}
+			return nil
+		})
+		if searcher.Checkpoint != nil {
+			searcher.Checkpoint.close()
+		}
+	}
 
-		return nil
-	})
+	if config.FileNameMode == fileNameAuto && fileCount == 1 && !searcher.stopped() {
+		dispatch(pending, false)
+	}
 
 	wg.Wait()
+	if pipeline != nil {
+		pipeline.wait()
+	}
 	if err != nil {
 		log.Printf("Error while walking the path: %v\n", err)
 	}
 }
 
-// searchInFile 搜索文件内容中符合模式的行
-func searchInFile(path string, matcher func(string) bool) {
+// matchFileMeta 判断文件的权限位及属主是否满足 -perm / -owner
+func matchFileMeta(config *Config, path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.Mode().Perm()&config.PermMask != config.PermMask {
+		return false
+	}
+	if config.OwnerUID >= 0 {
+		uid, ok := fileOwner(info)
+		return ok && uid == config.OwnerUID
+	}
+	return true
+}
+
+// matchFile 判断文件是否需要搜索：指定 -fp 时匹配相对搜索路径、以 / 分隔的完整路径，否则仅匹配文件名
+func matchFile(searcher *Searcher, path string) bool {
+	if searcher.PathRegex == nil {
+		return matchFileName(searcher.FileRegex, filepath.Base(path))
+	}
+	rel, err := filepath.Rel(searcher.Config.SearchPath, path)
+	if err != nil {
+		rel = path
+	}
+	return matchFileName(searcher.PathRegex, filepath.ToSlash(rel))
+}
+
+// matchFileName 判断文件名是否符合 -f 指定的模式
+func matchFileName(regex *regexp2.Regexp, name string) bool {
+	isMatch, err := regex.MatchString(name)
+	return err == nil && isMatch
+}
+
+// openFile 打开待搜索的文件，按 -maxsize、-dedup-inode 过滤掉的或无法打开的文件返回 nil
+func openFile(path string, searcher *Searcher) *os.File {
 	file, err := os.Open(path)
 	if err != nil {
 		log.Printf("Error opening file %s: %v\n", path, err)
+		return nil
+	}
+	if searcher.Config.MaxSize > 0 || searcher.Config.DedupInode {
+		info, err := file.Stat()
+		if (err == nil && searcher.Config.MaxSize > 0 && info.Size() > searcher.Config.MaxSize) ||
+			(err == nil && searcher.Config.DedupInode && !searcher.firstVisit(info)) {
+			file.Close()
+			return nil
+		}
+	}
+	return file
+}
+
+// searchInFile 搜索文件内容中符合模式的行；content 非 nil 时为 -readers 已读入的文件内容，不再打开文件
+func searchInFile(path string, showName bool, content []byte, searcher *Searcher) (matches int) {
+	if searcher.Checkpoint != nil {
+		defer searcher.Checkpoint.done(path)
+	}
+	var file *os.File
+	var reader io.Reader
+	if content != nil {
+		reader = bytes.NewReader(content)
+	} else {
+		start := time.Now()
+		if file = openFile(path, searcher); file == nil {
+			return
+		}
+		defer file.Close()
+
+		// -P auto 时统计 open/read 耗时占比，供并发数调整使用
+		reader = file
+		if searcher.Config.Sequential {
+			if searcher.sequentialReader == nil {
+				searcher.sequentialReader = bufio.NewReaderSize(file, sequentialBufferSize)
+			} else {
+				searcher.sequentialReader.Reset(file)
+			}
+			reader = searcher.sequentialReader
+		}
+		if searcher.Limiter != nil {
+			timed := &timedReader{reader: file, elapsed: time.Since(start)}
+			reader = timed
+			defer func() { searcher.Limiter.observe(timed.elapsed, time.Since(start)) }()
+		}
+	}
+
+	// -report-missing 记录已打开但没有匹配的文件，搜索被提前取消时文件可能未读完，不记录
+	if searcher.Config.ReportMissing {
+		displayPath := "./" + strings.ReplaceAll(path, "\\", "/")
+		defer func() {
+			if matches == 0 && !searcher.stopped() {
+				searcher.Summary.mu.Lock()
+				searcher.Summary.Unmatched = append(searcher.Summary.Unmatched, displayPath)
+				searcher.Summary.mu.Unlock()
+			}
+		}()
+	}
+
+	if searcher.Config.StatsByDir {
+		dir := topLevelDir(searcher.Config.SearchPath, path)
+		defer func() {
+			if matches > 0 {
+				searcher.Summary.mu.Lock()
+				searcher.Summary.Dirs[dir] += matches
+				searcher.Summary.mu.Unlock()
+			}
+		}()
+	}
+
+	if searcher.Config.Archive && isZipArchive(path) {
+		matches = searchInZip(path, file, showName, searcher)
+		return
+	}
+	if searcher.Config.Tar && isTarArchive(path) {
+		matches = searchInTar(path, reader, showName, searcher)
+		return
+	}
+	if searcher.Config.Gzip && isGzipFile(path) {
+		matches = searchInGzip(path, reader, showName, searcher)
+		return
+	}
+
+	// -w 时先读入全部内容并关闭文件，确保 Windows 上也能重命名覆盖
+	if searcher.Config.InPlace {
+		data, err := io.ReadAll(reader)
+		if file != nil {
+			file.Close()
+		}
+		if err != nil {
+			log.Printf("Error reading file %s: %v\n", path, err)
+			return
+		}
+		matches = replaceInFile(path, bytes.NewReader(data), showName, searcher)
+		return
+	}
+	if searcher.Config.ReplaceOut {
+		matches = printReplaced(path, reader, searcher)
 		return
 	}
-	defer file.Close()
 
 	// filepath.ToSlash(path)
 	path = "./" + strings.ReplaceAll(path, "\\", "/")
 
-	scanner := bufio.NewScanner(file)
+	// 指定 -encoding 时先整体解码为 UTF-8 再搜索
+	if len(searcher.Config.Encodings) > 0 {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			log.Printf("Error reading file %s: %v\n", path, err)
+			return
+		}
+		if data, err = decodeContent(data, searcher.Config.Encodings); err != nil {
+			searcher.Summary.mu.Lock()
+			searcher.Summary.Undecoded = append(searcher.Summary.Undecoded, path)
+			searcher.Summary.mu.Unlock()
+			return
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	return searchReader(path, reader, showName, searcher)
+}
+
+// topLevelDir 返回文件在搜索路径下的第一级目录，直接位于搜索路径下的文件归为 "."
+func topLevelDir(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "."
+	}
+	if i := strings.IndexRune(rel, filepath.Separator); i >= 0 {
+		return filepath.ToSlash(rel[:i])
+	}
+	return "."
+}
+
+// searchReader 逐行搜索 reader 中符合模式的行，path 用于输出及汇总，返回匹配行数
+func searchReader(path string, reader io.Reader, showName bool, searcher *Searcher) int {
+	config, summary := searcher.Config, searcher.Summary
+	if config.Whole {
+		return searchWhole(path, reader, searcher)
+	}
+	if config.HexPattern != nil {
+		return searchBytes(path, reader, searcher)
+	}
+
+	var hasCRLF, hasLF bool
+	var ranges []lineRange
+	if config.Ranges != nil {
+		ranges = config.Ranges[rangeKey(path)]
+	}
+	var tracker *nearTracker
+	if searcher.Near != nil {
+		tracker = searcher.Near.newTracker()
+	}
+	matches, lineNo := 0, 0
+	header, headerLine := "", 0
+	// -last 时保留最近一次匹配，读完文件后再输出；nth 为 -nth 下已遇到的匹配数，
+	// selected 表示 -first/-nth 已输出所选匹配，-crlf 时其后的行只用于检查换行符
+	var last *Match
+	var blamed []Match
+	nth := 0
+	selected := false
+	var keys *keyPathTracker
+	if config.KeyPath {
+		keys = &keyPathTracker{}
+	}
+	// -B 缓存最近的未匹配行，afterLeft 为 -A 尚需输出的行数
+	var before []Match
+	afterLeft := 0
+	scanner := bufio.NewScanner(reader)
+	lineBuf := lineBuffers.Get().(*[]byte)
+	defer lineBuffers.Put(lineBuf)
+	scanner.Buffer((*lineBuf)[:0], bufio.MaxScanTokenSize)
+	if config.NullData {
+		scanner.Split(scanNullRecords)
+	} else {
+		scanner.Split(scanLinesKeepEOL)
+	}
 	for scanner.Scan() {
-		line := strings.TrimRight(scanner.Text(), "\r\n")
-		if matcher(line) {
-			fmt.Printf("%s\t\t%s\n", path, line)
+		if searcher.stopped() {
+			break
+		}
+		lineNo++
+		raw := scanner.Text()
+		if strings.HasSuffix(raw, "\r\n") {
+			hasCRLF = true
+		} else if strings.HasSuffix(raw, "\n") {
+			hasLF = true
+		}
+		if selected {
+			continue
+		}
+
+		line := strings.TrimRight(raw, "\r\n")
+		if config.NonBlank && strings.TrimSpace(line) == "" {
+			continue
+		}
+		if ranges != nil && !inRanges(lineNo, ranges) {
+			continue
+		}
+		if tracker != nil {
+			for _, hit := range tracker.feed(lineNo, line) {
+				if !searcher.claimMatch() {
+					break
+				}
+				matches++
+				searcher.Printer.PrintMatch(Match{Path: path, Line: hit.lineNo, Text: hit.line}, showName)
+			}
+			continue
+		}
+
+		// 先更新标题再匹配：标题行本身匹配时，输出器据行号判断无需重复打印标题
+		if searcher.Header != nil && searcher.Header(line) {
+			header, headerLine = line, lineNo
+		}
+		if keys != nil {
+			keys.feed(line)
+		}
+
+		if searcher.Matcher(line) && searcher.claimMatch() {
+			// -nth 跳过第 N 个之前的匹配，它们不计入匹配数
+			if config.Nth > 0 {
+				if nth++; nth < config.Nth {
+					continue
+				}
+			}
+			matches++
+			if config.Frequency {
+				tokens := searcher.Tokenizer(line)
+				summary.mu.Lock()
+				for _, token := range tokens {
+					summary.Tokens[token]++
+				}
+				summary.mu.Unlock()
+				continue
+			}
+			if config.Histogram != "" {
+				recordTimestamp(summary, config, line)
+				continue
+			}
+			column, end := searcher.Locator(line)
+			if config.Snippet > 0 {
+				line = snippet(line, column, end, config.Snippet)
+			}
+			if searcher.Replacer != nil {
+				line = searcher.Replacer(line)
+			}
+			for _, prev := range before {
+				prev.Header, prev.HeaderLine = header, headerLine
+				searcher.Printer.PrintContext(prev, showName)
+			}
+			before = before[:0]
+			match := Match{Path: path, Line: lineNo, Col: column + 1, Text: line, Header: header, HeaderLine: headerLine}
+			if keys != nil {
+				match.KeyPath = keys.path()
+			}
+			if config.Last {
+				last = &match
+				continue
+			}
+			// -blame 收集文件中的全部匹配，读完后一次 git blame 注释
+			if config.Blame {
+				blamed = append(blamed, match)
+			} else {
+				searcher.Printer.PrintMatch(match, showName)
+			}
+			if config.First || config.Nth > 0 {
+				if !config.CheckEOL {
+					break
+				}
+				selected = true
+				continue
+			}
+			afterLeft = config.After
+		} else if afterLeft > 0 {
+			afterLeft--
+			searcher.Printer.PrintContext(Match{Path: path, Line: lineNo, Text: line, Header: header, HeaderLine: headerLine}, showName)
+		} else if config.Before > 0 {
+			if len(before) == config.Before {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, Match{Path: path, Line: lineNo, Text: line})
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		log.Printf("Error reading file %s: %v\n", path, err)
 	}
+	if last != nil {
+		if config.Blame {
+			blamed = append(blamed, *last)
+		} else {
+			searcher.Printer.PrintMatch(*last, showName)
+		}
+		matches = 1
+	}
+	if config.Blame {
+		printBlamed(searcher, strings.TrimPrefix(path, "./"), blamed, showName)
+	}
+
+	if config.JSONSummary {
+		recordExtension(summary, path, matches)
+	}
+
+	if config.CheckEOL && hasCRLF && hasLF {
+		summary.mu.Lock()
+		summary.MixedEOL = append(summary.MixedEOL, path)
+		summary.mu.Unlock()
+	}
+	return matches
+}
+
+// searchWhole 将 -whole 模式作用于整个文件内容，匹配时输出文件路径；超过 -maxsize 的内容视为不匹配
+func searchWhole(path string, reader io.Reader, searcher *Searcher) int {
+	config := searcher.Config
+	if config.MaxSize > 0 {
+		reader = io.LimitReader(reader, config.MaxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		log.Printf("Error reading file %s: %v\n", path, err)
+		return 0
+	}
+	if config.MaxSize > 0 && int64(len(data)) > config.MaxSize {
+		return 0
+	}
+	if !searcher.Matcher(string(data)) || !searcher.claimMatch() {
+		return 0
+	}
+
+	searcher.Printer.PrintFile(path)
+	if config.JSONSummary {
+		recordExtension(searcher.Summary, path, 1)
+	}
+	return 1
+}
+
+// recordExtension 按扩展名累计匹配数，供 -jsonsummary 输出
+func recordExtension(summary *Summary, path string, matches int) {
+	if matches == 0 {
+		return
+	}
+	ext := filepath.Ext(path)
+	if ext == "" {
+		ext = "(none)"
+	}
+	summary.mu.Lock()
+	summary.Extensions[ext] += matches
+	summary.mu.Unlock()
+}
+
+// scanLinesKeepEOL 与 bufio.ScanLines 类似，但保留行尾换行符，以便区分 \r\n 与 \n
+func scanLinesKeepEOL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// scanNullRecords 以 NUL 字节分隔记录，用于 -z
+func scanNullRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// printSummary 打印搜索结束后的汇总信息
+func printSummary(config *Config, summary *Summary) {
+	if config.CheckEOL && len(summary.MixedEOL) > 0 {
+		sort.Strings(summary.MixedEOL)
+		fmt.Printf("\nFiles with mixed line endings (CRLF and LF):\n")
+		for _, path := range summary.MixedEOL {
+			fmt.Println(path)
+		}
+	}
+
+	if len(summary.Undecoded) > 0 {
+		sort.Strings(summary.Undecoded)
+		fmt.Printf("\nFiles that could not be decoded with the requested encodings:\n")
+		for _, path := range summary.Undecoded {
+			fmt.Println(path)
+		}
+	}
+
+	if config.ReportMissing && len(summary.Unmatched) > 0 {
+		sort.Strings(summary.Unmatched)
+		fmt.Printf("\nNo matches in:\n")
+		for _, path := range summary.Unmatched {
+			fmt.Println(path)
+		}
+	}
+
+	if config.StatsByDir && len(summary.Dirs) > 0 {
+		dirs := make([]string, 0, len(summary.Dirs))
+		for dir := range summary.Dirs {
+			dirs = append(dirs, dir)
+		}
+		sort.Slice(dirs, func(i, j int) bool {
+			if summary.Dirs[dirs[i]] != summary.Dirs[dirs[j]] {
+				return summary.Dirs[dirs[i]] > summary.Dirs[dirs[j]]
+			}
+			return dirs[i] < dirs[j]
+		})
+		fmt.Printf("\nMatches by directory:\n")
+		for _, dir := range dirs {
+			fmt.Printf("%8d\t%s\n", summary.Dirs[dir], dir)
+		}
+	}
+
+	if config.Frequency && len(summary.Tokens) > 0 {
+		tokens := make([]string, 0, len(summary.Tokens))
+		for token := range summary.Tokens {
+			tokens = append(tokens, token)
+		}
+		sort.Slice(tokens, func(i, j int) bool {
+			if summary.Tokens[tokens[i]] != summary.Tokens[tokens[j]] {
+				return summary.Tokens[tokens[i]] > summary.Tokens[tokens[j]]
+			}
+			return tokens[i] < tokens[j]
+		})
+		for _, token := range tokens {
+			fmt.Printf("%8d\t%s\n", summary.Tokens[token], token)
+		}
+	}
+
+	if config.Histogram != "" {
+		printHistogram(summary)
+	}
+
+	if config.JSONSummary {
+		out, err := json.Marshal(summary.Extensions)
+		if err != nil {
+			log.Printf("Error encoding JSON summary: %v\n", err)
+			return
+		}
+		fmt.Printf("\n%s\n", out)
+	}
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseArgs 以 args 为命令行参数解析配置；每次使用新的 FlagSet，并屏蔽用户的 ~/.fsrc
+func parseArgs(tb testing.TB, args ...string) *Config {
+	tb.Helper()
+	return parseArgsWithHome(tb, tb.TempDir(), args...)
+}
+
+// parseArgsWithHome 同 parseArgs，但以 home 为 HOME，读取其中的 .fsrc
+func parseArgsWithHome(tb testing.TB, home string, args ...string) *Config {
+	tb.Helper()
+	tb.Setenv("HOME", home)
+	tb.Setenv("FS_EXCLUDE", "")
+	flag.CommandLine = flag.NewFlagSet("fs", flag.ExitOnError)
+	os.Args = append([]string{"fs"}, args...)
+	return parseAndValidateFlags()
+}
+
+// captureStdout 执行 fn 并返回其写到标准输出的内容
+func captureStdout(tb testing.TB, fn func()) string {
+	tb.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
+
+// writeCorpus 在 root 下生成 dirs 个目录、每个目录 files 个文件，每个文件 lines 行，每 10 行含一个 needle
+func writeCorpus(tb testing.TB, root string, dirs, files, lines int) {
+	tb.Helper()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("dir%02d", d))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			var content bytes.Buffer
+			for l := 0; l < lines; l++ {
+				if l%10 == 0 {
+					fmt.Fprintf(&content, "line %d of file %d in %d: needle\n", l, f, d)
+				} else {
+					fmt.Fprintf(&content, "line %d of file %d in %d: some ordinary text\n", l, f, d)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%03d.txt", f)), content.Bytes(), 0o644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSingleWorkerOutputIsReproducible(t *testing.T) {
+	root := t.TempDir()
+	writeCorpus(t, root, 8, 20, 30)
+	config := parseArgs(t, "-f", `\.txt$`, "-s", "needle", "-P", "1", root)
+
+	first := captureStdout(t, func() { search(config) })
+	second := captureStdout(t, func() { search(config) })
+	if !bytes.Contains([]byte(first), []byte("needle")) {
+		t.Fatalf("no matches in output:\n%s", first)
+	}
+	if first != second {
+		t.Errorf("-P 1 output differs between runs:\n--- first\n%s\n--- second\n%s", first, second)
+	}
+}
+
+// benchmarkSearch 在 b.N 次迭代中以 args 搜索 root，输出写入 /dev/null
+func benchmarkSearch(b *testing.B, root string, args ...string) {
+	config := parseArgs(b, append(args, root)...)
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		search(config)
+	}
+}
+
+// BenchmarkSearchMode 在小文件为主的语料上比较 -mode parallel 与 -mode sequential
+func BenchmarkSearchMode(b *testing.B) {
+	root := b.TempDir()
+	writeCorpus(b, root, 20, 50, 40)
+	for _, mode := range []string{"parallel", "sequential"} {
+		b.Run(mode, func(b *testing.B) {
+			benchmarkSearch(b, root, "-f", `\.txt$`, "-s", "needle", "-mode", mode)
+		})
+	}
+}
+
+func TestMixedEOLAfterSelectedMatch(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hit\nhit\r\nx\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, selector := range [][]string{{"-first"}, {"-nth", "1"}} {
+		config := parseArgs(t, append(append([]string{"-f", `\.txt$`, "-s", "hit", "-crlf"}, selector...), root)...)
+		out := captureStdout(t, func() { search(config) })
+		if !strings.Contains(out, "mixed line endings") {
+			t.Errorf("%v -crlf: mixed line endings after the selected match not reported:\n%s", selector, out)
+		}
+		if n := strings.Count(out, "\nhit\n"); n != 1 {
+			t.Errorf("%v -crlf: printed %d matches, want 1:\n%s", selector, n, out)
+		}
+	}
+}
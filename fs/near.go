@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/dlclark/regexp2"
+	"sort"
+)
+
+// NearMatcher 保存 -near 模式下的两个子模式及允许的最大行距
+type NearMatcher struct {
+	patterns [2]*regexp2.Regexp
+	distance int
+}
+
+// nearHit 表示 -near 模式下需要输出的一行
+type nearHit struct {
+	lineNo int
+	line   string
+}
+
+// nearTracker 记录单个文件中每个子模式最近一次匹配的行
+type nearTracker struct {
+	*NearMatcher
+	lastNo   [2]int
+	lastLine [2]string
+	printed  int
+}
+
+// createNearMatcher 创建 -near 匹配器，未指定 -near 时返回 nil
+func createNearMatcher(config *Config) *NearMatcher {
+	if len(config.NearPatterns) == 0 {
+		return nil
+	}
+	return &NearMatcher{
+		patterns: [2]*regexp2.Regexp{
+			regexp2.MustCompile(config.NearPatterns[0], regexp2.None),
+			regexp2.MustCompile(config.NearPatterns[1], regexp2.None),
+		},
+		distance: config.NearDistance,
+	}
+}
+
+// newTracker 为单个文件创建独立的匹配状态
+func (m *NearMatcher) newTracker() *nearTracker {
+	return &nearTracker{NearMatcher: m}
+}
+
+// feed 处理一行内容，当两个子模式在指定行距内均有匹配时返回尚未输出过的相关行
+func (t *nearTracker) feed(lineNo int, line string) []nearHit {
+	var matched [2]bool
+	for i, pattern := range t.patterns {
+		if ok, err := pattern.MatchString(line); err == nil && ok {
+			matched[i] = true
+		}
+	}
+
+	// 与另一子模式此前最近一次的匹配比较，同一行同时匹配两者也视为邻近
+	var hits []nearHit
+	current := false
+	for i := range matched {
+		other := 1 - i
+		if !matched[i] {
+			continue
+		}
+		if matched[other] {
+			current = true
+		}
+		if t.lastNo[other] != 0 && lineNo-t.lastNo[other] <= t.distance {
+			current = true
+			if t.lastNo[other] > t.printed {
+				hits = append(hits, nearHit{t.lastNo[other], t.lastLine[other]})
+			}
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].lineNo < hits[j].lineNo })
+	if current {
+		hits = append(hits, nearHit{lineNo, line})
+		t.printed = lineNo
+	}
+
+	for i := range matched {
+		if matched[i] {
+			t.lastNo[i], t.lastLine[i] = lineNo, line
+		}
+	}
+	return hits
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// reorderBuffer 实现 -mode ordered：每个调度的文件按遍历顺序获得一个序号，其输出先写入各自的缓冲区，
+// 轮到该序号且文件搜索完成时才写到标准输出。
+//
+// 调度方在文件输出之前不释放并发名额，因此缓冲区中最多同时有 -P 个文件：内存占用以 -P 个文件的输出为上限，
+// 代价是一个较慢的文件会阻塞其后已完成文件的输出，并暂停新文件的调度
+type reorderBuffer struct {
+	seqs    map[string]int // 显示路径到序号
+	pending map[int]*pendingOutput
+	count   int    // 已分配的序号数
+	next    int    // 下一个待输出的序号
+	release func() // 文件输出后释放其并发名额
+}
+
+// pendingOutput 是一个尚未输出的文件的结果
+type pendingOutput struct {
+	path string
+	buf  bytes.Buffer
+	done bool
+	outputState
+}
+
+// newReorderBuffer 创建重排缓冲区，release 在每个文件输出后调用一次
+func newReorderBuffer(release func()) *reorderBuffer {
+	return &reorderBuffer{seqs: make(map[string]int), pending: make(map[int]*pendingOutput), release: release}
+}
+
+// lookup 返回路径所属文件的缓冲区，压缩包内条目（archive!entry）归属压缩包本身
+func (r *reorderBuffer) lookup(path string) *pendingOutput {
+	seq, ok := r.seqs[path]
+	if !ok {
+		archive, _, found := strings.Cut(path, "!")
+		if !found {
+			return nil
+		}
+		if seq, ok = r.seqs[archive]; !ok {
+			return nil
+		}
+	}
+	return r.pending[seq]
+}
+
+// Begin 为即将搜索的文件分配序号，需按遍历顺序在调度时调用
+func (p *Printer) Begin(path string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.order
+	seq := r.count
+	r.count++
+	r.seqs[path] = seq
+	r.pending[seq] = &pendingOutput{path: path}
+	return seq
+}
+
+// End 标记文件搜索完成，并按序号输出所有已就绪的文件
+func (p *Printer) End(seq int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.order
+	r.pending[seq].done = true
+	for next := r.pending[r.next]; next != nil && next.done; next = r.pending[r.next] {
+		if next.buf.Len() > 0 {
+			if p.lastPath != "" {
+				if p.separator != "" {
+					fmt.Fprintln(os.Stdout, p.separator)
+				} else if separator := p.groupSeparator(); separator != "" {
+					fmt.Fprintln(os.Stdout, separator)
+				}
+			}
+			os.Stdout.Write(next.buf.Bytes())
+			p.lastPath = next.lastPath
+		}
+		delete(r.seqs, next.path)
+		delete(r.pending, r.next)
+		r.next++
+		r.release()
+	}
+}
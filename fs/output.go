@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Match 表示一条待输出的匹配结果，字段可在 -format 模板中引用
+type Match struct {
+	Path string // 文件路径
+	Line int    // 行号，从 1 开始
+	Col  int    // 第一个匹配的字节列号，从 1 开始；无法确定时为 0
+	Text string // 匹配行（已应用 -r 替换及 -trim）
+
+	Header     string // -context-header 下匹配行之前最近的标题行，没有时为空
+	HeaderLine int    // 标题行的行号，没有时为 0
+	Context    bool   // 是否为 -A/-B/-C 输出的上下文行
+	KeyPath    string // -keypath 下匹配行所在的 YAML 键路径
+	ID         int    // -id 下匹配的全局序号，从 1 开始；上下文行为 0
+	Blame      string // -blame 下最后修改该行的提交作者及短 SHA，不在 git 仓库中时为空
+}
+
+// outputState 记录一个输出流中最近一次输出所属的文件及标题
+type outputState struct {
+	lastPath string
+
+	// 最近一次打印的标题，同一标题下的连续匹配只打印一次标题
+	headerPath string
+	headerLine int
+}
+
+// Printer 串行化并发搜索产生的输出，并记录最近一次输出所属的文件
+type Printer struct {
+	mu        sync.Mutex
+	config    *Config
+	template  *template.Template
+	separator string
+	outputState
+
+	// 每个文件最后输出的行号，用于裁掉与已输出内容重叠的上下文行
+	printed map[string]int
+
+	// -mode ordered 时尚未输出的文件结果，为 nil 时直接写标准输出
+	order *reorderBuffer
+
+	// -id 已分配的匹配序号
+	lastID int
+
+	// -tree 收集的全部结果，搜索结束后由 printTree 统一输出
+	collected []Match
+}
+
+// newPrinter 创建输出器，-format 模板在此处编译一次
+func newPrinter(config *Config) *Printer {
+	printer := &Printer{config: config, printed: make(map[string]int)}
+	if config.Separate {
+		printer.separator = config.Separator
+	}
+	if config.Format != "" {
+		format := config.Format
+		if !strings.HasSuffix(format, "\n") {
+			format += "\n"
+		}
+		tmpl, err := template.New("format").Parse(format)
+		if err != nil {
+			log.Fatalf("Error: invalid -format template: %v\n", err)
+		}
+		printer.template = tmpl
+	}
+	return printer
+}
+
+// PrintMatch 按 -format、-vimgrep 或默认格式输出一条匹配结果
+func (p *Printer) PrintMatch(match Match, showName bool) {
+	// -trim 只影响输出，匹配仍基于原始行
+	if p.config.Trim {
+		match.Text = strings.TrimSpace(match.Text)
+		match.Header = strings.TrimSpace(match.Header)
+	}
+	// -format 模板可直接引用 .KeyPath，其余格式将键路径加在行首
+	if match.KeyPath != "" && p.template == nil {
+		match.Text = "[" + match.KeyPath + "] " + match.Text
+	}
+	if match.Blame != "" && p.template == nil {
+		match.Text = fmt.Sprintf("%d (%s): %s", match.Line, match.Blame, match.Text)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.Tree {
+		p.collected = append(p.collected, match)
+		return
+	}
+	w, state := p.target(match.Path)
+	p.separateGroup(w, state, match)
+	p.printed[match.Path] = match.Line
+	p.separate(w, state, match.Path)
+	p.printHeader(w, state, match, showName)
+	// 序号在锁内分配，非 -mode ordered 时输出中的序号依次递增
+	if p.config.MatchIDs {
+		p.lastID++
+		match.ID = p.lastID
+	}
+	if p.template == nil {
+		p.writeID(w, match.ID)
+	}
+
+	switch {
+	case p.template != nil:
+		if err := p.template.Execute(w, match); err != nil {
+			log.Printf("Error executing -format template: %v\n", err)
+		}
+	case p.config.VimGrep:
+		fmt.Fprintf(w, "%s:%d:%d:%s\n", match.Path, match.Line, match.Col, match.Text)
+	case p.config.Diff:
+		fmt.Fprintf(w, "%s:%d\t\t+%s\n", match.Path, match.Line, match.Text)
+	case len(p.config.NearPatterns) > 0 && showName:
+		fmt.Fprintf(w, "%s\t\t%d: %s\n", match.Path, match.Line, match.Text)
+	case len(p.config.NearPatterns) > 0:
+		fmt.Fprintf(w, "%d: %s\n", match.Line, match.Text)
+	case showName:
+		fmt.Fprintf(w, "%s\t\t%s\n", match.Path, match.Text)
+	default:
+		fmt.Fprintln(w, match.Text)
+	}
+}
+
+// PrintFile 输出 -whole 下匹配的文件路径，-format 模板中仅 .Path 有值
+func (p *Printer) PrintFile(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.Tree {
+		p.collected = append(p.collected, Match{Path: path})
+		return
+	}
+	w, _ := p.target(path)
+	match := Match{Path: path}
+	if p.config.MatchIDs {
+		p.lastID++
+		match.ID = p.lastID
+	}
+	if p.template != nil {
+		if err := p.template.Execute(w, match); err != nil {
+			log.Printf("Error executing -format template: %v\n", err)
+		}
+		return
+	}
+	p.writeID(w, match.ID)
+	fmt.Fprintln(w, path)
+}
+
+// PrintContent 原样输出 path 的一段完整内容，保证多个文件的内容不会交错
+func (p *Printer) PrintContent(path string, content []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	w, _ := p.target(path)
+	w.Write(content)
+}
+
+// PrintContext 输出一行上下文，已输出过的行（与前一个匹配的上下文重叠）不再重复输出；
+// -vimgrep 只输出匹配行
+func (p *Printer) PrintContext(match Match, showName bool) {
+	if p.config.VimGrep {
+		return
+	}
+	if p.config.Trim {
+		match.Text = strings.TrimSpace(match.Text)
+		match.Header = strings.TrimSpace(match.Header)
+	}
+	match.Context = true
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if match.Line <= p.printed[match.Path] {
+		return
+	}
+	w, state := p.target(match.Path)
+	p.separateGroup(w, state, match)
+	p.printed[match.Path] = match.Line
+	p.separate(w, state, match.Path)
+	p.printHeader(w, state, match, showName)
+
+	switch {
+	case p.template != nil:
+		if err := p.template.Execute(w, match); err != nil {
+			log.Printf("Error executing -format template: %v\n", err)
+		}
+	case showName:
+		p.writeID(w, 0)
+		fmt.Fprintf(w, "%s-\t\t%s\n", match.Path, match.Text)
+	default:
+		p.writeID(w, 0)
+		fmt.Fprintln(w, match.Text)
+	}
+}
+
+// printHeader 在匹配行之前打印其所属的 -context-header 标题，调用方需持有锁；
+// -format 模板可直接引用 .Header，-vimgrep 的输出需保持为纯 quickfix 格式，均不单独打印
+func (p *Printer) printHeader(w io.Writer, state *outputState, match Match, showName bool) {
+	if match.HeaderLine == 0 || (match.Path == state.headerPath && match.HeaderLine == state.headerLine) {
+		return
+	}
+	state.headerPath, state.headerLine = match.Path, match.HeaderLine
+	if p.template != nil || p.config.VimGrep || match.HeaderLine >= match.Line {
+		return
+	}
+	p.writeID(w, 0)
+	if showName {
+		fmt.Fprintf(w, "%s\t\t@@ %s\n", match.Path, match.Header)
+	} else {
+		fmt.Fprintf(w, "@@ %s\n", match.Header)
+	}
+}
+
+// writeID 在 -id 下输出行首的序号列，上下文行及标题行没有序号，以 - 占位
+func (p *Printer) writeID(w io.Writer, id int) {
+	switch {
+	case !p.config.MatchIDs:
+	case id == 0:
+		fmt.Fprint(w, "-\t")
+	default:
+		fmt.Fprintf(w, "%d\t", id)
+	}
+}
+
+// separate 在结果切换到新文件时打印分隔行，调用方需持有锁
+func (p *Printer) separate(w io.Writer, state *outputState, path string) {
+	if p.separator != "" && state.lastPath != "" && state.lastPath != path {
+		fmt.Fprintln(w, p.separator)
+	}
+	state.lastPath = path
+}
+
+// groupSeparator 返回 -A/-B/-C 下不相邻的上下文组之间的分隔行，与 grep 一致为 --；
+// -format 与 -vimgrep 的输出逐行独立，不使用分隔行
+func (p *Printer) groupSeparator() string {
+	if (p.config.Before == 0 && p.config.After == 0) || p.template != nil || p.config.VimGrep {
+		return ""
+	}
+	return "--"
+}
+
+// separateGroup 在与上一次输出不相邻的行之前打印 --：同一文件中行号不连续，或切换到另一个文件
+// （已由 -sep 分隔时除外）。需在更新 printed 与 lastPath 之前调用，调用方需持有锁
+func (p *Printer) separateGroup(w io.Writer, state *outputState, match Match) {
+	separator := p.groupSeparator()
+	if separator == "" || state.lastPath == "" {
+		return
+	}
+	if state.lastPath != match.Path {
+		if p.separator == "" {
+			fmt.Fprintln(w, separator)
+		}
+		return
+	}
+	if last, ok := p.printed[match.Path]; ok && match.Line > last+1 {
+		fmt.Fprintln(w, separator)
+	}
+}
+
+// target 返回 path 的输出目标及其输出状态：-mode ordered 下尚未轮到的文件写入各自的缓冲区，
+// 其余情况写标准输出。调用方需持有锁
+func (p *Printer) target(path string) (io.Writer, *outputState) {
+	if p.order != nil {
+		if pending := p.order.lookup(path); pending != nil {
+			return &pending.buf, &pending.outputState
+		}
+	}
+	return os.Stdout, &p.outputState
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestContextGroupSeparator 不相邻的上下文组之间以 -- 分隔，相邻或重叠的组合并输出
+func TestContextGroupSeparator(t *testing.T) {
+	root := t.TempDir()
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines[4], lines[6], lines[19] = "hit 5", "hit 7", "hit 20"
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := parseArgs(t, "-f", `\.txt$`, "-s", "hit", "-C", "1", root)
+
+	out := captureStdout(t, func() { search(config) })
+	if n := strings.Count(out, "\n--\n"); n != 1 {
+		t.Fatalf("want 1 group separator, got %d:\n%s", n, out)
+	}
+	before, after, _ := strings.Cut(out, "\n--\n")
+	if !strings.Contains(before, "line 8") || strings.Contains(before, "line 19") {
+		t.Errorf("first group should end at line 8:\n%s", out)
+	}
+	if !strings.HasPrefix(after, "line 19\n") {
+		t.Errorf("second group should start at line 19:\n%s", out)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultPager 未设置 $PAGER 时使用的分页器
+const defaultPager = "less -R"
+
+// startPager 启动 $PAGER 并将 os.Stdout 重定向到其标准输入，返回的函数关闭输入并等待分页器退出；
+// 标准输出不是终端或分页器无法启动时直接输出到终端
+func startPager() func() {
+	if !isTerminal(os.Stdout) {
+		return func() {}
+	}
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+	fields := strings.Fields(pager)
+	if len(fields) == 0 {
+		return func() {}
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = reader, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		reader.Close()
+		writer.Close()
+		return func() {}
+	}
+	reader.Close()
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	return func() {
+		writer.Close()
+		cmd.Wait()
+		os.Stdout = stdout
+	}
+}
+
+// isTerminal 判断文件是否为终端
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// loadPatterns 读取 -pf 文件，每行一个模式，空行及 # 开头的行被忽略；
+// 所有模式合并为一个按“或”匹配的正则，字面量模式（未指定 -ssf）会先转义
+func loadPatterns(path string, regex bool) (string, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	var alternatives []string
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !regex {
+			line = regexp2.Escape(line)
+		} else if _, err := regexp2.Compile(line, regexp2.None); err != nil {
+			return "", 0, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+		alternatives = append(alternatives, "(?:"+line+")")
+	}
+	if err := scanner.Err(); err != nil {
+		return "", 0, err
+	}
+	if len(alternatives) == 0 {
+		return "", 0, fmt.Errorf("%s: no patterns found", path)
+	}
+	return strings.Join(alternatives, "|"), len(alternatives), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lineRange 表示闭区间 [start, end] 的行号范围
+type lineRange struct {
+	start, end int
+}
+
+// loadRanges 读取 -ranges 文件，每行格式为 path:start-end 或 path:line，空行及 # 开头的行被忽略
+func loadRanges(path string) (map[string][]lineRange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ranges := make(map[string][]lineRange)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sep := strings.LastIndex(line, ":")
+		if sep <= 0 {
+			return nil, fmt.Errorf("%s:%d: expected path:start-end", path, lineNo)
+		}
+		r, err := parseLineRange(line[sep+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+		key := rangeKey(line[:sep])
+		ranges[key] = append(ranges[key], r)
+	}
+	return ranges, scanner.Err()
+}
+
+// parseLineRange 解析 start-end 或单个行号
+func parseLineRange(text string) (lineRange, error) {
+	startText, endText, found := strings.Cut(text, "-")
+	if !found {
+		endText = startText
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(startText))
+	if err != nil {
+		return lineRange{}, fmt.Errorf("invalid start line %q", startText)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endText))
+	if err != nil {
+		return lineRange{}, fmt.Errorf("invalid end line %q", endText)
+	}
+	if start < 1 || end < start {
+		return lineRange{}, fmt.Errorf("invalid line range %q", text)
+	}
+	return lineRange{start, end}, nil
+}
+
+// rangeKey 规范化路径，使 -ranges 中的路径与遍历得到的路径可以直接比较
+func rangeKey(path string) string {
+	return filepath.Clean(filepath.FromSlash(strings.TrimPrefix(path, "./")))
+}
+
+// inRanges 判断行号是否落在任一范围内
+func inRanges(lineNo int, ranges []lineRange) bool {
+	for _, r := range ranges {
+		if lineNo >= r.start && lineNo <= r.end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRCFile(t *testing.T) {
+	home := t.TempDir()
+	rc := "# defaults\nexclude=vendor,node_modules\nparallelism=3\ntrim=true\nw=true\ncolor=true\nhidden=false\n"
+	if err := os.WriteFile(filepath.Join(home, ".fsrc"), []byte(rc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := parseArgsWithHome(t, home, "-s", "needle", "-P", "5", t.TempDir())
+	if want := []string{"vendor", "node_modules"}; !reflect.DeepEqual(config.ExclusionPaths, want) {
+		t.Errorf("ExclusionPaths = %v, want %v from exclude", config.ExclusionPaths, want)
+	}
+	if config.Parallelism != 5 {
+		t.Errorf("Parallelism = %d, want 5: an explicit -P wins over parallelism", config.Parallelism)
+	}
+	if !config.Trim {
+		t.Error("Trim = false, want true from trim")
+	}
+	if config.InPlace {
+		t.Error("InPlace = true: w is not an allowed ~/.fsrc key")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"log"
+	"sync"
+)
+
+// loadedFile 是 -readers 已读入内存、等待搜索的文件，压缩包的 content 为 nil
+type loadedFile struct {
+	path     string
+	showName bool
+	content  []byte
+}
+
+// readPipeline 实现 -readers：文件的打开读取与搜索分为两级。最多 -readers 个文件同时处于打开、读取或等待交付的状态，
+// 读入的内容经缓冲通道交给固定的 -P 个搜索 goroutine；读取受 I/O 限制、正则匹配受 CPU 限制，两者的并发数可分别调整
+type readPipeline struct {
+	searcher  *Searcher
+	readers   chan struct{}
+	loaded    chan loadedFile
+	reading   sync.WaitGroup
+	searching sync.WaitGroup
+}
+
+// startReadPipeline 启动 -P 个搜索 goroutine
+func startReadPipeline(searcher *Searcher) *readPipeline {
+	config := searcher.Config
+	p := &readPipeline{
+		searcher: searcher,
+		readers:  make(chan struct{}, config.Readers),
+		loaded:   make(chan loadedFile, config.Parallelism),
+	}
+	for i := 0; i < config.Parallelism; i++ {
+		p.searching.Add(1)
+		go func() {
+			defer p.searching.Done()
+			for file := range p.loaded {
+				searchInFile(file.path, file.showName, file.content, searcher)
+			}
+		}()
+	}
+	return p
+}
+
+// dispatch 在读取名额可用时读入文件，内容交付给搜索 goroutine 后才释放名额，因此内存中等待搜索的文件数同样有上限
+func (p *readPipeline) dispatch(path string, showName bool) {
+	p.readers <- struct{}{}
+	p.reading.Add(1)
+	go func() {
+		defer p.reading.Done()
+		defer func() { <-p.readers }()
+		// zip 需要随机访问，由搜索 goroutine 自行打开
+		if p.searcher.Config.Archive && isZipArchive(path) {
+			p.loaded <- loadedFile{path: path, showName: showName}
+			return
+		}
+		if content, ok := loadFile(path, p.searcher); ok {
+			p.loaded <- loadedFile{path, showName, content}
+		} else if p.searcher.Checkpoint != nil {
+			p.searcher.Checkpoint.done(path)
+		}
+	}()
+}
+
+// wait 等待所有已调度的文件读取并搜索完成
+func (p *readPipeline) wait() {
+	p.reading.Wait()
+	close(p.loaded)
+	p.searching.Wait()
+}
+
+// loadFile 打开并读入整个文件，按 -maxsize、-dedup-inode 过滤掉的文件返回 false
+func loadFile(path string, searcher *Searcher) ([]byte, bool) {
+	file := openFile(path, searcher)
+	if file == nil {
+		return nil, false
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("Error reading file %s: %v\n", path, err)
+		return nil, false
+	}
+	return content, true
+}
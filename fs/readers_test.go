@@ -0,0 +1,20 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+// BenchmarkSearchReaders 比较搜索与读取共用 -P 的默认模式和 -readers 分开限制读取并发时的吞吐量
+func BenchmarkSearchReaders(b *testing.B) {
+	root := b.TempDir()
+	writeCorpus(b, root, 20, 50, 200)
+	b.Run("default", func(b *testing.B) {
+		benchmarkSearch(b, root, "-f", `\.txt$`, "-ss", `needle|file \d+ in 7:`, "-P", "4")
+	})
+	for _, readers := range []int{2, 8, 32} {
+		b.Run("readers="+strconv.Itoa(readers), func(b *testing.B) {
+			benchmarkSearch(b, root, "-f", `\.txt$`, "-ss", `needle|file \d+ in 7:`, "-P", "4", "-readers", strconv.Itoa(readers))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// replaceInFile 对文件中匹配的行应用 -r 替换并原地写回（-w），返回替换的行数
+func replaceInFile(path string, reader io.Reader, showName bool, searcher *Searcher) int {
+	displayPath := "./" + strings.ReplaceAll(path, "\\", "/")
+	content, matches, err := replaceLines(displayPath, reader, showName, true, searcher)
+	if err != nil {
+		log.Printf("Error reading file %s: %v\n", displayPath, err)
+		return 0
+	}
+
+	if matches > 0 {
+		if err := writeFileAtomic(path, content); err != nil {
+			log.Printf("Error writing file %s: %v\n", displayPath, err)
+		}
+	}
+	return matches
+}
+
+// printReplaced 将应用 -r 替换后的完整文件内容输出到标准输出（-rout），不修改文件，返回替换的行数
+func printReplaced(path string, reader io.Reader, searcher *Searcher) int {
+	displayPath := "./" + strings.ReplaceAll(path, "\\", "/")
+	content, matches, err := replaceLines(displayPath, reader, false, false, searcher)
+	if err != nil {
+		log.Printf("Error reading file %s: %v\n", displayPath, err)
+		return 0
+	}
+
+	if matches > 0 {
+		searcher.Printer.PrintContent(displayPath, content)
+	}
+	return matches
+}
+
+// replaceLines 对匹配的行应用替换并保留原有换行符，返回替换后的全部内容及替换的行数；report 时逐条输出替换结果
+func replaceLines(displayPath string, reader io.Reader, showName, report bool, searcher *Searcher) ([]byte, int, error) {
+	var content bytes.Buffer
+	matches, lineNo := 0, 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanLinesKeepEOL)
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+		line := strings.TrimRight(raw, "\r\n")
+		if searcher.Matcher(line) {
+			matches++
+			replaced := searcher.Replacer(line)
+			if report {
+				column, _ := searcher.Locator(line)
+				searcher.Printer.PrintMatch(Match{Path: displayPath, Line: lineNo, Col: column + 1, Text: replaced}, showName)
+			}
+			raw = replaced + raw[len(line):]
+		}
+		content.WriteString(raw)
+	}
+	return content.Bytes(), matches, scanner.Err()
+}
+
+// renameFile 用于 writeFileAtomic 的最后一步，测试中可替换以模拟重命名前崩溃
+var renameFile = os.Rename
+
+// writeFileAtomic 先写入同目录下的临时文件并 fsync，再重命名覆盖原文件，
+// 保证进程崩溃时原文件要么保持原样、要么是完整的新内容；尽量保留原文件的权限和属主
+func writeFileAtomic(path string, data []byte) (err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".fs-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	preserveOwner(tmp.Name(), info)
+	return renameFile(tmp.Name(), path)
+}
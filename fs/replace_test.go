@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yml")
+	if err := os.WriteFile(path, []byte("old\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new\n")); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new\n" {
+		t.Errorf("content = %q, want %q", data, "new\n")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+	}
+}
+
+// TestWriteFileAtomicCrashBeforeRename 模拟临时文件已写入、重命名之前进程失败的情况
+func TestWriteFileAtomicCrashBeforeRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yml")
+	if err := os.WriteFile(path, []byte("original\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	crash := errors.New("simulated crash")
+	renameFile = func(string, string) error { return crash }
+	defer func() { renameFile = os.Rename }()
+
+	if err := writeFileAtomic(path, []byte("half-written")); !errors.Is(err, crash) {
+		t.Fatalf("writeFileAtomic error = %v, want %v", err, crash)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "original\n" {
+		t.Errorf("original file changed to %q", data)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary file left behind: %d entries in %s", len(entries), dir)
+	}
+}
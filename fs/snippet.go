@@ -0,0 +1,26 @@
+package main
+
+import (
+	"unicode/utf8"
+)
+
+// snippetEllipsis 标记 -snippet 截断的一端
+const snippetEllipsis = "…"
+
+// snippet 截取行内匹配部分及其前后各 n 个字符，被截断的一端加上省略号；
+// 未能定位匹配位置（start < 0）时从行首开始截取
+func snippet(line string, start, end, n int) string {
+	if start < 0 {
+		start, end = 0, 0
+	}
+	head, tail := line[:start], line[end:]
+	prefix, suffix := "", ""
+	if utf8.RuneCountInString(head) > n {
+		runes := []rune(head)
+		head, prefix = string(runes[len(runes)-n:]), snippetEllipsis
+	}
+	if utf8.RuneCountInString(tail) > n {
+		tail, suffix = string([]rune(tail)[:n]), snippetEllipsis
+	}
+	return prefix + head + line[start:end] + tail + suffix
+}
@@ -0,0 +1,26 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// preserveOwner 非 Unix 系统上没有 uid/gid 属主信息，不做处理
+func preserveOwner(path string, info os.FileInfo) {}
+
+// fileOwner 非 Unix 系统上无法获取 uid 属主
+func fileOwner(info os.FileInfo) (int, bool) {
+	return 0, false
+}
+
+// lookupOwner 非 Unix 系统不支持 -owner
+func lookupOwner(owner string) (int, error) {
+	return 0, errors.New("-owner is only supported on Unix systems")
+}
+
+// fileID 非 Unix 系统上无法获取 inode，-dedup-inode 不生效
+func fileID(info os.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// preserveOwner 尽量将文件属主设置为与 info 一致，无权限时忽略
+func preserveOwner(path string, info os.FileInfo) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = os.Lchown(path, int(stat.Uid), int(stat.Gid))
+	}
+}
+
+// fileOwner 返回文件属主的 uid
+func fileOwner(info os.FileInfo) (int, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Uid), true
+	}
+	return 0, false
+}
+
+// lookupOwner 将 -owner 指定的用户名或数字 uid 解析为 uid
+func lookupOwner(owner string) (int, error) {
+	if uid, err := strconv.Atoi(owner); err == nil && uid >= 0 {
+		return uid, nil
+	}
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, fmt.Errorf("unknown -owner %q: %v", owner, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// fileID 返回文件所在设备及 inode，用于识别硬链接、符号链接指向的同一文件
+func fileID(info os.FileInfo) (fileKey, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileKey{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+	}
+	return fileKey{}, false
+}
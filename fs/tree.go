@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// printTree 输出 -tree 收集的全部结果：按路径排序后以目录为标题逐级缩进，文件位于所在目录之下，
+// 匹配行再缩进一级；-whole 下只有文件
+func (p *Printer) printTree() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	matches := p.collected
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].Line < matches[j].Line
+	})
+
+	var dirs []string
+	lastPath := ""
+	for _, match := range matches {
+		parts := strings.Split(strings.TrimPrefix(match.Path, "./"), "/")
+		if match.Path != lastPath {
+			lastPath = match.Path
+			// 与上一个文件的公共目录前缀不再重复输出
+			common := 0
+			for common < len(dirs) && common < len(parts)-1 && dirs[common] == parts[common] {
+				common++
+			}
+			dirs = append(dirs[:common], parts[common:len(parts)-1]...)
+			for depth := common; depth < len(dirs); depth++ {
+				fmt.Fprintf(os.Stdout, "%s%s/\n", strings.Repeat("  ", depth), dirs[depth])
+			}
+			fmt.Fprintf(os.Stdout, "%s%s\n", strings.Repeat("  ", len(dirs)), parts[len(parts)-1])
+		}
+		if match.Line > 0 {
+			fmt.Fprintf(os.Stdout, "%s%d: %s\n", strings.Repeat("  ", len(dirs)+2), match.Line, match.Text)
+		}
+	}
+	p.collected = nil
+}
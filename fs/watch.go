@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dlclark/regexp2"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce 文件变化后等待的时间，用于合并编辑器保存文件时产生的一连串事件
+const watchDebounce = 300 * time.Millisecond
+
+// watchAndSearch 实现 -watch：监视搜索路径下除 -e 外的所有目录，符合 -f（或 -fp）的文件变化时清屏并重新搜索，
+// 直到进程被中断
+func watchAndSearch(config *Config) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Fatalf("Error: cannot watch %s: %v\n", config.SearchPath, err)
+	}
+	defer watcher.Close()
+	addWatches(watcher, config, config.SearchPath)
+
+	// 只用于按文件名或路径过滤事件
+	filter := &Searcher{Config: config, FileRegex: regexp2.MustCompile(config.FilePattern, regexp2.None)}
+	if config.PathPattern != "" {
+		filter.PathRegex = regexp2.MustCompile(config.PathPattern, regexp2.None)
+	}
+
+	var rerun <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// fsnotify 不递归监视，新建的目录需要单独加入
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					addWatches(watcher, config, event.Name)
+					continue
+				}
+			}
+			if isExcluded(event.Name, config.ExclusionPaths) || !matchFile(filter, event.Name) {
+				continue
+			}
+			rerun = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Error watching files: %v\n", err)
+		case <-rerun:
+			rerun = nil
+			fmt.Print("\033[H\033[2J")
+			search(config)
+		}
+	}
+}
+
+// addWatches 监视 root 及其下所有未被 -e 排除的目录
+func addWatches(watcher *fsnotify.Watcher, config *Config, root string) {
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if isExcluded(path, config.ExclusionPaths) || isPruned(config, path) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			log.Printf("Error watching %s: %v\n", path, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestBareRepoDiscoveredWithoutSuffix(t *testing.T) {
+	base := t.TempDir()
+	seed := filepath.Join(t.TempDir(), "seed")
+	initRepo(t, seed)
+	git(t, base, "clone", "-q", "--bare", seed, filepath.Join(base, "plainbare"))
+	git(t, base, "clone", "-q", "--bare", seed, filepath.Join(base, "mirror.git"))
+	initRepo(t, filepath.Join(base, "work"))
+
+	var status RepoStatus
+	processRepos(base, &Config{Ctx: context.Background(), Branch: "main", Parallelism: 2, Marker: ".git"}, &status)
+	for _, name := range []string{"plainbare", "mirror.git"} {
+		if !contains(status.BareRepos, name) {
+			t.Errorf("BareRepos = %v, want %s", status.BareRepos, name)
+		}
+	}
+	if contains(status.BareRepos, "work") {
+		t.Errorf("BareRepos = %v, work has a working tree", status.BareRepos)
+	}
+	if status.Considered != 3 {
+		t.Errorf("Considered = %d, want 3", status.Considered)
+	}
+}
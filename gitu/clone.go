@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CloneFailure 记录克隆失败的仓库地址及原因
+type CloneFailure struct {
+	URL string
+	Err string
+}
+
+// cloneTask 为清单中的一项：仓库地址及目标目录
+type cloneTask struct {
+	url string
+	dir string
+}
+
+// readManifest 读取克隆清单，每行为 "url [dir]"，空行及 # 开头的行被忽略；未指定目录时取地址末段去掉 .git
+func readManifest(manifestPath string) ([]cloneTask, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tasks []cloneTask
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		task := cloneTask{url: fields[0]}
+		if len(fields) > 1 {
+			task.dir = fields[1]
+		} else {
+			task.dir = strings.TrimSuffix(path.Base(strings.TrimRight(task.url, "/")), ".git")
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+// cloneRepos 按清单并行克隆缺失的仓库，每完成一个即输出进度，失败的记录到 CloneFailures
+func cloneRepos(baseDir string, config *Config, repoStatus *RepoStatus) {
+	tasks, err := readManifest(config.Manifest)
+	if err != nil {
+		log.Fatalf("Failed to read manifest: %v", err)
+	}
+
+	var pending []cloneTask
+	for _, task := range tasks {
+		if _, err := os.Stat(filepath.Join(baseDir, task.dir)); err == nil {
+			continue
+		}
+		pending = append(pending, task)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, config.Parallelism)
+	done := 0
+	for _, task := range pending {
+		sem <- struct{}{}
+		if config.Ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(task cloneTask) {
+			defer wg.Done()
+			out, err := cloneRepo(baseDir, task, config)
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if err != nil {
+				reason := strings.TrimSpace(string(out))
+				if reason == "" {
+					reason = err.Error()
+				}
+				repoStatus.CloneFailures = append(repoStatus.CloneFailures, CloneFailure{task.url, reason})
+				failRepo(config, repoStatus, task.dir)
+				log.Printf("[%d/%d] Failed to clone %s: %v", done, len(pending), task.url, err)
+			} else {
+				repoStatus.Cloned = append(repoStatus.Cloned, task.dir)
+				log.Printf("[%d/%d] Cloned %s", done, len(pending), task.dir)
+			}
+		}(task)
+	}
+	wg.Wait()
+}
+
+// cloneRepo 克隆单个仓库，设置了 -timeout 时超时会终止 git 进程并清理未完成的目录
+func cloneRepo(baseDir string, task cloneTask, config *Config) ([]byte, error) {
+	ctx, cancel := commandContext(config)
+	defer cancel()
+
+	target := filepath.Join(baseDir, task.dir)
+	out, err := exec.CommandContext(ctx, "git", "clone", "--quiet", task.url, target).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		os.RemoveAll(target)
+		return nil, fmt.Errorf("timed out after %s", config.Timeout)
+	}
+	return out, err
+}
+
+func printCloneFailures(config *Config, failures []CloneFailure) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", colorize(config, colorRed, "Clone failures"))
+	for _, failure := range failures {
+		fmt.Printf("%s\t%s\n", failure.URL, failure.Err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// RepoState 记录 -csv 所需的单个仓库状态，在仓库处理完成后采集
+type RepoState struct {
+	Name   string
+	Branch string
+	Dirty  bool
+	Ahead  string
+	Behind string
+}
+
+var csvHeader = []string{"repo", "branch", "dirty", "ahead", "behind", "outcome"}
+
+// collectRepoState 采集仓库当前分支、是否有未提交改动以及相对上游的领先、落后提交数，没有上游时后两者为空
+func collectRepoState(repoPath, projectName string) RepoState {
+	return RepoState{
+		Name:   projectName,
+		Branch: runGitCommand(repoPath, "rev-parse", "--abbrev-ref", "HEAD"),
+		Dirty:  hasUncommittedChanges()(repoPath),
+		Ahead:  runGitCommand(repoPath, "rev-list", "--count", "@{u}..HEAD"),
+		Behind: runGitCommand(repoPath, "rev-list", "--count", "HEAD..@{u}"),
+	}
+}
+
+// writeCSV 按仓库名排序写出每个仓库一行的 CSV，outcome 取自报告中该仓库所在的分组
+func writeCSV(path string, repoStatus RepoStatus) error {
+	outcomes := make(map[string]string)
+	groups := []struct {
+		Outcome string
+		List    []string
+	}{
+		{"bare-fetched", repoStatus.BareRepos},
+		{"up-to-date", repoStatus.NoUpdates},
+		{"no-remote", repoStatus.NoRemote},
+		{"unpushed", repoStatus.UnpushedCommits},
+		{"uncommitted", repoStatus.UncommittedChanges},
+		{"not-on-branch", repoStatus.NotOnBranch},
+		{"updated", repoStatus.UpdatedRepos},
+		{"conflict", repoStatus.Conflicts},
+	}
+	// 后面的分组优先级更高，覆盖同一仓库在前面分组中的结果
+	for _, group := range groups {
+		for _, name := range group.List {
+			outcomes[name] = group.Outcome
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	states := repoStatus.States
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	writer := csv.NewWriter(file)
+	writer.Write(csvHeader)
+	for _, state := range states {
+		outcome := outcomes[state.Name]
+		if outcome == "" {
+			outcome = "pull-failed"
+		}
+		writer.Write([]string{state.Name, state.Branch, strconv.FormatBool(state.Dirty), state.Ahead, state.Behind, outcome})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RepoSize 记录 -du 统计的仓库占用空间（字节）
+type RepoSize struct {
+	Name     string
+	WorkTree int64
+	GitDir   int64
+}
+
+// repoSize 统计工作区文件（不含 .git 及子模块的 .git）的大小，git 目录的大小取自 git count-objects -v；
+// 裸仓库没有工作区
+func repoSize(repoPath, projectName string) RepoSize {
+	size := RepoSize{Name: projectName}
+	for _, line := range strings.Split(runGitCommand(repoPath, "count-objects", "-v"), "\n") {
+		key, value, found := strings.Cut(line, ": ")
+		if !found || (key != "size" && key != "size-pack" && key != "size-garbage") {
+			continue
+		}
+		if kib, err := strconv.ParseInt(value, 10, 64); err == nil {
+			size.GitDir += kib * 1024
+		}
+	}
+	if isBareRepo(repoPath) {
+		return size
+	}
+
+	filepath.WalkDir(repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size.WorkTree += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// printSizes 按总大小降序输出占用空间最大的 top 个仓库
+func printSizes(top int, sizes []RepoSize) {
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].WorkTree+sizes[i].GitDir > sizes[j].WorkTree+sizes[j].GitDir
+	})
+	if len(sizes) > top {
+		sizes = sizes[:top]
+	}
+	if len(sizes) == 0 {
+		return
+	}
+	fmt.Printf("\nLargest repositories (total, work tree, git):\n")
+	for _, size := range sizes {
+		fmt.Printf("%10s %10s %10s  %s\n", formatSize(size.WorkTree+size.GitDir), formatSize(size.WorkTree), formatSize(size.GitDir), size.Name)
+	}
+}
+
+// formatSize 以 1024 为进制输出可读的大小
+func formatSize(bytes int64) string {
+	const units = "KMGTPE"
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value, unit := float64(bytes)/1024, 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f%ciB", value, units[unit])
+}
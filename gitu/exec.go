@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// runExec 在仓库目录中通过 shell 执行命令（-exec / -after）并记录输出，设置了 -timeout 时超时会终止命令
+func runExec(repoPath, command string, config *Config) bool {
+	projectName := filepath.Base(repoPath)
+	ctx, cancel := commandContext(config)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	cmd.Dir = repoPath
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Command timed out after %s in %s:\n%s", config.Timeout, projectName, out)
+		return false
+	}
+	if err != nil {
+		log.Printf("Command failed in %s: %v\n%s", projectName, err, out)
+		return false
+	}
+	log.Printf("Ran command in %s:\n%s", projectName, out)
+	return true
+}
@@ -0,0 +1,20 @@
+package main
+
+import "context"
+
+// commandContext 返回执行单个命令的上下文：基于 config.Ctx，设置了 -timeout 时附带超时。
+// 调用方需在命令结束后调用返回的 cancel
+func commandContext(config *Config) (context.Context, context.CancelFunc) {
+	if config.Timeout > 0 {
+		return context.WithTimeout(config.Ctx, config.Timeout)
+	}
+	return config.Ctx, func() {}
+}
+
+// failRepo 在 -fail-fast 下记录第一个出错的仓库并取消 config.Ctx，调用方需持有锁
+func failRepo(config *Config, repoStatus *RepoStatus, projectName string) {
+	if config.FailFast && repoStatus.FailedRepo == "" {
+		repoStatus.FailedRepo = projectName
+		config.Cancel()
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fetchRepo 从所有远程获取更新而不修改工作区，仅在 -v 时输出每个仓库的日志
+func fetchRepo(repoPath string, config *Config) bool {
+	projectName := filepath.Base(repoPath)
+	out, err := runGitAction(config.Ctx, repoPath, "fetch", "--all", "--prune")
+	if err != nil {
+		if config.Verbose {
+			log.Printf("Failed to fetch %s: %v\n%s", projectName, err, out)
+		}
+		return false
+	}
+	if config.Verbose {
+		log.Printf("Fetched %s", projectName)
+	}
+	return true
+}
+
+// progressBar 在终端上以回车覆盖的方式显示 已完成/总数，总数随遍历发现的仓库增长
+type progressBar struct {
+	label string
+	total atomic.Int64
+	done  atomic.Int64
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// startProgress 在标准错误为终端时启动进度显示，否则返回 nil；nil 上的方法均为空操作
+func startProgress(label string) *progressBar {
+	if !isTerminal(os.Stderr) {
+		return nil
+	}
+	bar := &progressBar{label: label, quit: make(chan struct{})}
+	bar.wg.Add(1)
+	go func() {
+		defer bar.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				bar.render()
+			case <-bar.quit:
+				bar.render()
+				fmt.Fprintln(os.Stderr)
+				return
+			}
+		}
+	}()
+	return bar
+}
+
+func (b *progressBar) add() {
+	if b != nil {
+		b.total.Add(1)
+	}
+}
+
+func (b *progressBar) finish() {
+	if b != nil {
+		b.done.Add(1)
+	}
+}
+
+func (b *progressBar) stop() {
+	if b != nil {
+		close(b.quit)
+		b.wg.Wait()
+	}
+}
+
+func (b *progressBar) render() {
+	fmt.Fprintf(os.Stderr, "\r%s %d/%d", b.label, b.done.Load(), b.total.Load())
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// repoName 返回报告中使用的仓库名：-group 时为相对 config.BaseDir（即工作目录）的路径（斜杠分隔），否则为目录名
+func repoName(config *Config, repoPath string) string {
+	if config.Group {
+		if rel, err := filepath.Rel(config.BaseDir, repoPath); err == nil && rel != "." {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(repoPath)
+}
+
+// relativePath 返回仓库相对 config.BaseDir 的路径，用于 -suggest 的 cd 命令，使其可在工作目录中直接执行
+func relativePath(config *Config, repoPath string) string {
+	if rel, err := filepath.Rel(config.BaseDir, repoPath); err == nil {
+		return rel
+	}
+	return repoPath
+}
+
+// groupNames 按父目录将 -group 下的仓库名分组，返回排序后的父目录列表，位于基准目录本身的仓库归入 "."
+func groupNames(names []string) ([]string, map[string][]string) {
+	groups := make(map[string][]string)
+	for _, name := range names {
+		parent := path.Dir(name)
+		groups[parent] = append(groups[parent], name)
+	}
+	parents := make([]string, 0, len(groups))
+	for parent := range groups {
+		parents = append(parents, parent)
+	}
+	sort.Strings(parents)
+	return parents, groups
+}
+
+// printGroupedList 以父目录为小标题输出 printList 的条目
+func printGroupedList(items []string) {
+	parents, groups := groupNames(items)
+	for _, parent := range parents {
+		names := make([]string, len(groups[parent]))
+		for i, name := range groups[parent] {
+			names[i] = path.Base(name)
+		}
+		fmt.Printf("%s/\n  %s\n", parent, strings.Join(names, ", "))
+	}
+}
+
+// printGroupedItems 以父目录为小标题输出 printRepoItems 的条目，repos 需已按名称排序
+func printGroupedItems(repos []RepoItems) {
+	names := make([]string, len(repos))
+	items := make(map[string][]string, len(repos))
+	for i, repo := range repos {
+		names[i] = repo.Name
+		items[repo.Name] = repo.Items
+	}
+	parents, groups := groupNames(names)
+	for _, parent := range parents {
+		fmt.Printf("%s/\n", parent)
+		for _, name := range groups[parent] {
+			fmt.Printf("  %s\t%s\n", path.Base(name), strings.Join(items[name], ", "))
+		}
+	}
+}
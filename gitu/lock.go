@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	lockFilePrefix   = "gitu-"
+	lockPollInterval = time.Second
+	// 锁文件刚创建、尚未写入 PID 时的宽限期，超过后仍无法读取 PID 视为残留锁
+	lockWriteGrace = 5 * time.Second
+)
+
+// lockPath 返回工作区对应的锁文件路径。锁文件放在临时目录中、以 baseDir 绝对路径的哈希命名，
+// 避免在仓库根目录运行时成为未跟踪文件，被误报为未提交改动、被 -commit 提交或被 -clean 删除
+func lockPath(baseDir string) string {
+	if abs, err := filepath.Abs(baseDir); err == nil {
+		baseDir = abs
+	}
+	sum := sha256.Sum256([]byte(baseDir))
+	return filepath.Join(os.TempDir(), lockFilePrefix+hex.EncodeToString(sum[:8])+".lock")
+}
+
+// acquireLock 为 baseDir 创建锁文件，防止多个 gitu 同时在同一工作区运行。
+// 锁被其他存活进程持有时，wait 为 true 则轮询等待，否则返回错误；持有进程已不存在的残留锁会被清除。
+// 返回的 release 用于释放锁，进程收到中断信号时也会自动释放。
+func acquireLock(baseDir string, wait bool) (release func(), err error) {
+	path := lockPath(baseDir)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return releaseOnExit(path), nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		pid, stale := lockOwner(path)
+		if stale {
+			log.Printf("Removing stale lock %s (pid %d)", path, pid)
+			os.Remove(path)
+			continue
+		}
+		if !wait {
+			return nil, fmt.Errorf("another gitu run (pid %d) holds %s; use -wait to wait for it", pid, path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// lockOwner 读取锁文件中的 PID，并判断该锁是否为崩溃进程遗留的残留锁
+func lockOwner(path string) (pid int, stale bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	content, err := os.ReadFile(path)
+	if err == nil {
+		pid, err = strconv.Atoi(strings.TrimSpace(string(content)))
+	}
+	if err != nil {
+		return 0, time.Since(info.ModTime()) > lockWriteGrace
+	}
+	return pid, !processAlive(pid)
+}
+
+// processAlive 判断指定 PID 的进程是否仍在运行
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Windows 上 FindProcess 成功即说明进程存在
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// releaseOnExit 返回释放锁的函数，并在收到中断信号时释放锁后退出
+func releaseOnExit(path string) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		os.Remove(path)
+		os.Exit(1)
+	}()
+	return func() {
+		signal.Stop(signals)
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry 为 -since 汇总的一条提交
+type LogEntry struct {
+	Repo    string
+	SHA     string
+	Time    time.Time
+	Subject string
+}
+
+// collectLog 读取仓库当前分支在 since 之后的提交
+func collectLog(repoPath, projectName, since string) []LogEntry {
+	out := runGitCommand(repoPath, "log", "--since="+since, "--format=%ct%x09%h%x09%s")
+	if out == "" {
+		return nil
+	}
+	var entries []LogEntry
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, LogEntry{projectName, fields[1], time.Unix(seconds, 0), fields[2]})
+	}
+	return entries
+}
+
+// printLog 将所有仓库的提交按提交时间从新到旧合并输出
+func printLog(config *Config, entries []LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	fmt.Printf("\n%s:\n", colorize(config, colorGreen, "Commits since "+config.Since))
+	for _, entry := range entries {
+		fmt.Printf("%s\t%s\t%s\t%s\n", entry.Repo, entry.SHA, entry.Time.Format("2006-01-02 15:04"), entry.Subject)
+	}
+}
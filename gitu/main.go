@@ -1,44 +1,375 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Config struct {
-	Branch      string
-	Parallelism int
+	Branch        string
+	Parallelism   int
+	Only          *regexp.Regexp
+	Wait          bool
+	Timing        int
+	Color         bool
+	DefaultBranch bool
+	CommitMessage string
+	Signoff       bool
+	Push          bool
+	PushTags      string
+	Checkout      bool
+	Manifest      string
+	Timeout       time.Duration
+	Marker        string
+	Exec          string
+	Orphans       bool
+	Clean         bool
+	Force         bool
+	CSV           string
+	Fetch         bool
+	Verbose       bool
+	CheckRemotes  bool
+	Suggest       bool
+	CompareFrom   string
+	CompareTo     string
+	Snapshot      string
+	Restore       map[string]RepoSnapshot
+	After         string
+	FFOnly        bool
+	Since         string
+	SetUpstream   bool
+	Identity      bool
+	ExpectEmail   string
+	FailFast      bool
+	MirrorTo      string
+	ResetHard     bool
+	Baseline      map[string]RepoSnapshot
+	DiskUsage     int
+	NewBranch     string
+	VerifySig     bool
+	Preview       bool
+	PruneMerged   bool
+	Base          string
+	Group         bool
+	BaseDir       string
+	Ctx           context.Context
+	Cancel        context.CancelFunc
 }
 
+// ANSI 颜色，用于按类别区分报告中的各个分组
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
 type RepoStatus struct {
 	NotOnBranch        []string
 	UncommittedChanges []string
 	UnpushedCommits    []string
 	UpdatedRepos       []string
 	NoUpdates          []string
+	Conflicts          []string
+	Committed          []string
+	CheckedOut         []string
+	BranchMissing      []string
+	BranchCreated      []string
+	BranchExists       []string
+	BranchFailures     []string
+	HasStash           []string
+	SubmoduleDrift     []string
+	TagsPushed         []string
+	TagsOnRemote       []string
+	TagPushFailures    []string
+	ExecSucceeded      []string
+	ExecFailed         []string
+	OrphanBranches     []RepoItems
+	Cleaned            []RepoItems
+	Reset              []RepoItems
+	ResetFailed        []string
+	States             []RepoState
+	Fetched            []string
+	FetchFailed        []string
+	Mirrored           []string
+	MirrorFailed       []string
+	Unreachable        []string
+	Divergence         []RepoItems
+	CompareMissing     []string
+	Snapshots          map[string]RepoSnapshot
+	Restored           []string
+	RestoreFailed      []string
+	NotInSnapshot      []string
+	BaselineDrift      []RepoItems
+	HookFailures       []string
+	NeedsMerge         []string
+	Log                []LogEntry
+	UpstreamSet        []string
+	WrongIdentity      []RepoItems
+	MissingIdentity    []string
+	Unsigned           []string
+	SigUnverifiable    []string
+	BareRepos          []string
+	Cloned             []string
+	CloneFailures      []CloneFailure
+	FailedRepo         string
+	Paths              map[string][]string
+	Repos              []string
+	Considered         int
+	Skipped            int
+	Timings            []RepoTiming
+	Sizes              []RepoSize
+	Preview            []RepoItems
+	Pruned             []RepoItems
+	PruneFailed        []string
+	BaseDrift          []RepoItems
+	BaseMissing        []string
+	NoRemote           []string
+	Elapsed            time.Duration
+}
+
+// RepoItems 记录某个仓库对应的明细，如没有上游的分支、被清理的文件
+type RepoItems struct {
+	Name  string
+	Items []string
+}
+
+type RepoTiming struct {
+	Name     string
+	Duration time.Duration
 }
 
 func main() {
 	config := parseFlags()
 	currentDir := getCurrentDir()
+	config.BaseDir = currentDir
+	// 所有修改仓库或访问远程的 git 命令以及 -exec/-after 命令共用 config.Ctx，
+	// -fail-fast 下第一个仓库出错时被取消，正在执行的命令随之终止，尚未开始的仓库不再处理
+	config.Ctx, config.Cancel = context.WithCancel(context.Background())
+	defer config.Cancel()
 
+	release, err := acquireLock(currentDir, config.Wait)
+	if err != nil {
+		log.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer release()
+
+	start := time.Now()
 	repoStatus := RepoStatus{}
-	processRepos(currentDir, config, &repoStatus)
-	printResults(config.Branch, repoStatus)
+	if config.Manifest != "" {
+		cloneRepos(currentDir, config, &repoStatus)
+	}
+	// -preview 先只获取并列出即将带入的提交，确认后才按常规流程更新
+	if config.Preview {
+		previewStatus := RepoStatus{}
+		processRepos(currentDir, config, &previewStatus)
+		if previewStatus.FailedRepo != "" {
+			release()
+			log.Fatalf("Aborted: %s failed (-fail-fast)", previewStatus.FailedRepo)
+		}
+		printPreview(config, previewStatus.Preview)
+		if len(previewStatus.Preview) == 0 || !confirm("Pull the repositories with incoming commits?") {
+			return
+		}
+		// 确认后处理预览过的仓库，不再重新查找仓库和探测远程，沿用预览时的统计与不可达的远程
+		config.Preview = false
+		repoStatus.Considered, repoStatus.Skipped = previewStatus.Considered, previewStatus.Skipped
+		repoStatus.Unreachable, repoStatus.Paths = previewStatus.Unreachable, previewStatus.Paths
+		processRepoList(previewStatus.Repos, config, &repoStatus)
+	} else {
+		processRepos(currentDir, config, &repoStatus)
+	}
+	if repoStatus.FailedRepo != "" {
+		release()
+		log.Fatalf("Aborted: %s failed (-fail-fast)", repoStatus.FailedRepo)
+	}
+	repoStatus.Elapsed = time.Since(start)
+	if config.Snapshot != "" {
+		if err := writeSnapshot(config.Snapshot, repoStatus.Snapshots); err != nil {
+			log.Printf("Failed to write snapshot %s: %v", config.Snapshot, err)
+		} else {
+			log.Printf("Wrote snapshot of %d repositories to %s", len(repoStatus.Snapshots), config.Snapshot)
+		}
+	}
+	if config.CSV != "" {
+		if err := writeCSV(config.CSV, repoStatus); err != nil {
+			log.Printf("Failed to write CSV %s: %v", config.CSV, err)
+		}
+	}
+	printResults(config, repoStatus)
 }
 
 func parseFlags() *Config {
 	branch := flag.String("b", "master", "Branch name to check and update")
 	parallelism := flag.Int("p", runtime.NumCPU()*10, "Parallelism level")
+	only := flag.String("only", "", "Only process repositories whose name matches this regex")
+	timing := flag.Int("timing", 0, "Print total elapsed time and the N slowest repositories")
+	diskUsage := flag.Int("du", 0, "Print the N largest repositories by disk usage (work tree plus git objects)")
+	defaultBranch := flag.Bool("default-branch", false, "Use each repository's default branch (origin/HEAD) instead of -b, falling back to -b")
+	commitMessage := flag.String("commit", "", "Commit uncommitted changes with this message before checking")
+	signoff := flag.Bool("signoff", false, "Add a Signed-off-by trailer to commits created by -commit")
+	push := flag.Bool("push", false, "Push commits created by -commit, or the branch created by -new-branch")
+	newBranch := flag.String("new-branch", "", "Create and check out this branch from the current HEAD in every clean repository instead of updating")
+	mirrorTo := flag.String("mirror-to", "", "Push every repository with --mirror to a \"backup\" remote at <baseURL>/<repo>.git instead of updating")
+	failFast := flag.Bool("fail-fast", false, "Stop all repositories at the first git or command error and exit nonzero, naming the failing repository")
+	pruneMerged := flag.Bool("prune-merged", false, "Delete local branches already merged into the default branch, except the current one; only lists them unless -force is given")
+	preview := flag.Bool("preview", false, "Fetch and list the commits a pull would bring into each repository, then ask before updating")
+	verifySig := flag.Bool("verify-sig", false, "Report repositories whose HEAD commit has no valid GPG/SSH signature (git verify-commit)")
+	identity := flag.Bool("identity", false, "Report repositories with no user.email configured, or one different from -expect-email")
+	expectEmail := flag.String("expect-email", "", "Expected user.email for -identity, e.g. you@work.example")
+	setUpstream := flag.Bool("set-upstream", false, "When pull fails because the branch has no upstream, track origin/<branch> and retry")
+	since := flag.String("since", "", "Print commits made since this date (e.g. \"2 weeks ago\") across all repositories as one feed instead of updating")
+	ffOnly := flag.Bool("ff-only", false, "Only fast-forward on pull; diverged repositories are reported instead of merged")
+	after := flag.String("after", "", "Run this shell command in each repository after a successful pull, e.g. \"make build\"")
+	snapshot := flag.String("snapshot", "", "Write each repository's HEAD commit and origin URL to this JSON file instead of updating")
+	baseline := flag.String("baseline", "", "Report whether each HEAD moved forward, backward or diverged from the commit recorded in this -snapshot file instead of updating")
+	restore := flag.String("restore", "", "Check out each clean repository at the commit recorded in this -snapshot file instead of updating")
+	group := flag.Bool("group", false, "Group the repositories in each report section by their parent directory relative to the current directory")
+	base := flag.String("base", "", "Report how many commits the current branch is ahead of and behind this base, e.g. origin/develop")
+	compare := flag.String("compare", "", "Report how many commits b has that a does not (a..b) in each repository")
+	suggest := flag.Bool("suggest", false, "Print a suggested git command for each flagged repository in the report")
+	checkRemotes := flag.Bool("check-remotes", false, "Check that every remote is reachable (git ls-remote) before the run and skip repositories whose remote is not")
+	fetch := flag.Bool("fetch", false, "Only fetch every repository, showing a progress bar on a terminal, instead of checking and updating")
+	verbose := flag.Bool("v", false, "Log each repository in -fetch mode")
+	csvPath := flag.String("csv", "", "Write one row per repository (branch, dirty, ahead, behind, outcome) to this CSV file")
+	resetHard := flag.Bool("reset-hard", false, "Fetch and reset the target branch to origin/<branch>, discarding local changes and commits; only lists them unless -force is given")
+	clean := flag.Bool("clean", false, "Remove untracked files and directories (git clean -d); only lists them unless -force is given")
+	force := flag.Bool("force", false, "Confirm destructive operations such as -clean")
+	orphans := flag.Bool("orphan-branches", false, "Report local branches that have no upstream")
+	pushTags := flag.String("push-tags", "", "Push local tags missing on origin: \"all\" or a single tag name")
+	checkout := flag.Bool("checkout", false, "Check out the target branch in clean repositories that are on another branch")
+	manifest := flag.String("clone", "", "Clone repositories listed in this manifest file (url [dir] per line) before updating")
+	timeout := flag.Duration("timeout", 0, "Timeout for each clone, -exec or -after command and -check-remotes probe, e.g. 2m (0 means no timeout)")
+	marker := flag.String("marker", ".git", "Treat directories containing this entry as repositories (other markers require -exec)")
+	execCommand := flag.String("exec", "", "Run this shell command in each repository instead of checking and updating it")
+	color := flag.String("color", "auto", "Colorize the report: auto, always or never")
+	wait := flag.Bool("wait", false, "Wait for another running gitu in the same directory instead of exiting")
 	flag.Parse()
-	return &Config{*branch, *parallelism}
+
+	config := &Config{
+		Branch:        *branch,
+		Parallelism:   *parallelism,
+		Wait:          *wait,
+		Timing:        *timing,
+		DiskUsage:     *diskUsage,
+		NewBranch:     *newBranch,
+		VerifySig:     *verifySig,
+		Preview:       *preview,
+		PruneMerged:   *pruneMerged,
+		Base:          *base,
+		Group:         *group,
+		DefaultBranch: *defaultBranch,
+		CommitMessage: *commitMessage,
+		Signoff:       *signoff,
+		Push:          *push,
+		PushTags:      *pushTags,
+		Checkout:      *checkout,
+		Manifest:      *manifest,
+		Timeout:       *timeout,
+		Marker:        *marker,
+		Exec:          *execCommand,
+		Orphans:       *orphans,
+		Clean:         *clean,
+		Force:         *force,
+		CSV:           *csvPath,
+		Fetch:         *fetch,
+		Verbose:       *verbose,
+		CheckRemotes:  *checkRemotes,
+		Suggest:       *suggest,
+		Snapshot:      *snapshot,
+		After:         *after,
+		FFOnly:        *ffOnly,
+		Since:         *since,
+		SetUpstream:   *setUpstream,
+		Identity:      *identity,
+		ExpectEmail:   *expectEmail,
+		FailFast:      *failFast,
+		MirrorTo:      *mirrorTo,
+		ResetHard:     *resetHard,
+	}
+	switch *color {
+	case "auto":
+		config.Color = isTerminal(os.Stdout)
+	case "always":
+		config.Color = true
+	case "never":
+	default:
+		log.Fatalf("Invalid -color value %q: must be auto, always or never", *color)
+	}
+	if config.Marker != ".git" && config.Exec == "" {
+		log.Fatalf("-marker %s requires -exec: only git repositories can be checked and updated", config.Marker)
+	}
+	if config.ExpectEmail != "" && !config.Identity {
+		log.Fatalf("-expect-email requires -identity")
+	}
+	// 以下模式各自替代默认的检查与更新流程，同时指定多个时只会执行其中一个，因此直接拒绝
+	var modes []string
+	for _, mode := range []struct {
+		name string
+		set  bool
+	}{
+		{"-exec", *execCommand != ""},
+		{"-fetch", *fetch},
+		{"-mirror-to", *mirrorTo != ""},
+		{"-preview", *preview},
+		{"-since", *since != ""},
+		{"-snapshot", *snapshot != ""},
+		{"-restore", *restore != ""},
+		{"-baseline", *baseline != ""},
+		{"-new-branch", *newBranch != ""},
+	} {
+		if mode.set {
+			modes = append(modes, mode.name)
+		}
+	}
+	if len(modes) > 1 {
+		log.Fatalf("%s are mutually exclusive", strings.Join(modes, ", "))
+	}
+	if *baseline != "" {
+		snapshots, err := readSnapshot(*baseline)
+		if err != nil {
+			log.Fatalf("Failed to read baseline: %v", err)
+		}
+		config.Baseline = snapshots
+	}
+	if *restore != "" {
+		snapshots, err := readSnapshot(*restore)
+		if err != nil {
+			log.Fatalf("Failed to read snapshot: %v", err)
+		}
+		config.Restore = snapshots
+	}
+	if *compare != "" {
+		from, to, found := strings.Cut(*compare, "..")
+		if !found || from == "" || to == "" || strings.HasPrefix(to, ".") {
+			log.Fatalf("Invalid -compare value %q: must be a..b", *compare)
+		}
+		config.CompareFrom, config.CompareTo = from, to
+	}
+	if *only != "" {
+		regex, err := regexp.Compile(*only)
+		if err != nil {
+			log.Fatalf("Invalid -only pattern: %v", err)
+		}
+		config.Only = regex
+	}
+	return config
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 func getCurrentDir() string {
@@ -49,24 +380,105 @@ func getCurrentDir() string {
 	return dir
 }
 
-func processRepos(baseDir string, config *Config, repoStatus *RepoStatus) {
+// startDispatch 返回以 -p 限制并发处理单个仓库的 dispatch 及等待全部处理完成的 wait；
+// dispatch 只能在同一个 goroutine 中调用，处理过的仓库按顺序记录在 repoStatus.Repos 中
+func startDispatch(config *Config, repoStatus *RepoStatus) (func(string), func()) {
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, config.Parallelism)
 	var mu sync.Mutex
+	var bar *progressBar
+	if config.Fetch {
+		bar = startProgress("Fetched")
+	}
 
+	dispatch := func(repoPath string) {
+		bar.add()
+		sem <- struct{}{}
+		if config.Ctx.Err() != nil {
+			<-sem
+			return
+		}
+		repoStatus.Repos = append(repoStatus.Repos, repoPath)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repoStart := time.Now()
+			processRepo(repoPath, config, repoStatus, &mu)
+			bar.finish()
+			if config.Timing > 0 {
+				mu.Lock()
+				repoStatus.Timings = append(repoStatus.Timings, RepoTiming{repoName(config, repoPath), time.Since(repoStart)})
+				mu.Unlock()
+			}
+			<-sem
+		}()
+	}
+	wait := func() {
+		wg.Wait()
+		bar.stop()
+	}
+	return dispatch, wait
+}
+
+// processRepoList 处理 -preview 确认后的仓库，沿用预览时查找并检查过远程的仓库列表
+func processRepoList(repos []string, config *Config, repoStatus *RepoStatus) {
+	dispatch, wait := startDispatch(config, repoStatus)
+	for _, repoPath := range repos {
+		dispatch(repoPath)
+	}
+	wait()
+}
+
+func processRepos(baseDir string, config *Config, repoStatus *RepoStatus) {
+	dispatch, wait := startDispatch(config, repoStatus)
+
+	// -check-remotes 时先收集所有仓库，待远程检查完成后只处理远程可达的仓库
+	var pending []string
 	err := filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() && filepath.Base(path) == ".git" {
-			repoPath := filepath.Dir(path)
-			sem <- struct{}{}
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				processRepo(repoPath, config.Branch, repoStatus, &mu)
-				<-sem
-			}()
+		if config.Ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		// .git 可以是目录，也可以是链接 worktree 使用的指向真实 git 目录的文件；
+		// 子模块的 .git 文件指向上级仓库的 .git/modules，不作为独立仓库处理，以免拉取后偏离上级仓库固定的提交；
+		// 具有 HEAD、objects、refs 布局的其他目录可能是裸仓库（如镜像），此时仓库即该目录本身；
+		// 指定了其他 -marker 时，包含该条目的目录即为仓库
+		var repoPath string
+		switch {
+		case config.Marker != ".git":
+			if filepath.Base(path) != config.Marker {
+				return nil
+			}
+			repoPath = filepath.Dir(path)
+		case filepath.Base(path) == ".git" && (info.IsDir() || isLinkedWorktree(path)):
+			repoPath = filepath.Dir(path)
+		case info.IsDir() && hasGitDirLayout(path) && isBareRepo(path):
+			repoPath = path
+		default:
+			return nil
+		}
+
+		if config.Only != nil && !config.Only.MatchString(filepath.Base(repoPath)) {
+			repoStatus.Skipped++
+		} else {
+			repoStatus.Considered++
+			if config.Suggest {
+				if repoStatus.Paths == nil {
+					repoStatus.Paths = make(map[string][]string)
+				}
+				name := repoName(config, repoPath)
+				repoStatus.Paths[name] = append(repoStatus.Paths[name], relativePath(config, repoPath))
+			}
+			if config.CheckRemotes {
+				pending = append(pending, repoPath)
+			} else {
+				dispatch(repoPath)
+			}
+		}
+
+		if info.IsDir() {
 			return filepath.SkipDir
 		}
 		return nil
@@ -74,21 +486,307 @@ func processRepos(baseDir string, config *Config, repoStatus *RepoStatus) {
 	if err != nil {
 		log.Printf("Error walking directories: %v", err)
 	}
-	wg.Wait()
+	for _, repoPath := range checkRemotes(pending, config, repoStatus) {
+		dispatch(repoPath)
+	}
+	wait()
 }
 
-func processRepo(repoPath, branch string, repoStatus *RepoStatus, mu *sync.Mutex) {
-	projectName := filepath.Base(repoPath)
+// resolveGitFile 解析 "gitdir: <path>" 格式的 .git 文件，返回存在的真实 git 目录，否则返回空串
+func resolveGitFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !found {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return gitDir
+}
+
+// isLinkedWorktree 判断 .git 文件是否属于 git worktree add 创建的链接 worktree，
+// 其 git 目录位于 <commondir>/worktrees/<name>
+func isLinkedWorktree(path string) bool {
+	gitDir := resolveGitFile(path)
+	return gitDir != "" && filepath.Base(filepath.Dir(gitDir)) == "worktrees"
+}
+
+func processRepo(repoPath string, config *Config, repoStatus *RepoStatus, mu *sync.Mutex) {
+	projectName := repoName(config, repoPath)
+	if config.Exec != "" {
+		list := &repoStatus.ExecSucceeded
+		if !runExec(repoPath, config.Exec, config) {
+			list = &repoStatus.ExecFailed
+		}
+		mu.Lock()
+		*list = append(*list, projectName)
+		if list == &repoStatus.ExecFailed {
+			failRepo(config, repoStatus, projectName)
+		}
+		mu.Unlock()
+		return
+	}
+	if config.Fetch {
+		list := &repoStatus.Fetched
+		if !fetchRepo(repoPath, config) {
+			list = &repoStatus.FetchFailed
+		}
+		mu.Lock()
+		*list = append(*list, projectName)
+		if list == &repoStatus.FetchFailed {
+			failRepo(config, repoStatus, projectName)
+		}
+		mu.Unlock()
+		return
+	}
+	if config.MirrorTo != "" {
+		list := &repoStatus.Mirrored
+		if !mirrorRepo(repoPath, config) {
+			list = &repoStatus.MirrorFailed
+		}
+		mu.Lock()
+		*list = append(*list, projectName)
+		if list == &repoStatus.MirrorFailed {
+			failRepo(config, repoStatus, projectName)
+		}
+		mu.Unlock()
+		return
+	}
+	if config.Preview {
+		if isBareRepo(repoPath) {
+			return
+		}
+		if commits := previewRepo(config.Ctx, repoPath); len(commits) > 0 {
+			mu.Lock()
+			repoStatus.Preview = append(repoStatus.Preview, RepoItems{projectName, commits})
+			mu.Unlock()
+		}
+		return
+	}
+	if config.Since != "" {
+		entries := collectLog(repoPath, projectName, config.Since)
+		mu.Lock()
+		repoStatus.Log = append(repoStatus.Log, entries...)
+		mu.Unlock()
+		return
+	}
+	if config.Snapshot != "" {
+		if snapshot, ok := snapshotRepo(repoPath); ok {
+			mu.Lock()
+			if repoStatus.Snapshots == nil {
+				repoStatus.Snapshots = make(map[string]RepoSnapshot)
+			}
+			repoStatus.Snapshots[filepath.Base(repoPath)] = snapshot
+			mu.Unlock()
+		}
+		return
+	}
+	// 裸仓库没有工作区，-restore 时跳过
+	if config.Restore != nil {
+		if isBareRepo(repoPath) {
+			return
+		}
+		list := &repoStatus.NotInSnapshot
+		if snapshot, found := config.Restore[filepath.Base(repoPath)]; found {
+			list = &repoStatus.Restored
+			if !restoreRepo(config.Ctx, repoPath, snapshot) {
+				list = &repoStatus.RestoreFailed
+			}
+		}
+		mu.Lock()
+		*list = append(*list, projectName)
+		if list == &repoStatus.RestoreFailed {
+			failRepo(config, repoStatus, projectName)
+		}
+		mu.Unlock()
+		return
+	}
+	if config.Baseline != nil {
+		snapshot, found := config.Baseline[filepath.Base(repoPath)]
+		var drift string
+		if found {
+			drift = compareBaseline(repoPath, snapshot.SHA)
+		}
+		mu.Lock()
+		if found {
+			repoStatus.BaselineDrift = append(repoStatus.BaselineDrift, RepoItems{projectName, []string{drift}})
+		} else {
+			repoStatus.NotInSnapshot = append(repoStatus.NotInSnapshot, projectName)
+		}
+		mu.Unlock()
+		return
+	}
+	// -new-branch 跳过裸仓库及有未提交改动的仓库
+	if config.NewBranch != "" {
+		if isBareRepo(repoPath) {
+			return
+		}
+		var list *[]string
+		if hasUncommittedChanges()(repoPath) {
+			log.Printf("Not creating branch %s in %s: it has uncommitted changes", config.NewBranch, projectName)
+			list = &repoStatus.UncommittedChanges
+		} else {
+			switch createBranch(repoPath, config) {
+			case newBranchCreated:
+				list = &repoStatus.BranchCreated
+			case newBranchExists:
+				list = &repoStatus.BranchExists
+			case newBranchFailed:
+				list = &repoStatus.BranchFailures
+			}
+		}
+		mu.Lock()
+		*list = append(*list, projectName)
+		if list == &repoStatus.BranchFailures {
+			failRepo(config, repoStatus, projectName)
+		}
+		mu.Unlock()
+		return
+	}
+	// -csv 在仓库处理完成后采集最终状态
+	if config.CSV != "" {
+		defer func() {
+			state := collectRepoState(repoPath, projectName)
+			mu.Lock()
+			repoStatus.States = append(repoStatus.States, state)
+			mu.Unlock()
+		}()
+	}
+	// -du 只读统计，与状态检查一同进行，裸仓库也参与统计
+	if config.DiskUsage > 0 {
+		size := repoSize(repoPath, projectName)
+		mu.Lock()
+		repoStatus.Sizes = append(repoStatus.Sizes, size)
+		mu.Unlock()
+	}
+	if isBareRepo(repoPath) {
+		fetchBareRepo(config.Ctx, repoPath)
+		mu.Lock()
+		repoStatus.BareRepos = append(repoStatus.BareRepos, projectName)
+		mu.Unlock()
+		return
+	}
+
+	branch := config.Branch
+	if config.DefaultBranch {
+		if defaultBranch := getDefaultBranch(repoPath); defaultBranch != "" {
+			branch = defaultBranch
+		}
+	}
+
+	// -checkout 时将不在目标分支上的干净仓库切换到目标分支
+	if config.Checkout && notOnBranch(branch)(repoPath) && !hasUncommittedChanges()(repoPath) {
+		list := &repoStatus.CheckedOut
+		switch checkoutBranch(config.Ctx, repoPath, branch) {
+		case checkoutMissing:
+			list = &repoStatus.BranchMissing
+		case checkoutFailed:
+			list = nil
+			mu.Lock()
+			failRepo(config, repoStatus, projectName)
+			mu.Unlock()
+		}
+		if list != nil {
+			mu.Lock()
+			*list = append(*list, projectName)
+			mu.Unlock()
+		}
+	}
+
+	// -clean 在提交和检查之前清理未跟踪文件，未指定 -force 时仅列出将被删除的文件
+	if config.Clean {
+		if files := cleanUntracked(config.Ctx, repoPath, config.Force); len(files) > 0 {
+			mu.Lock()
+			repoStatus.Cleaned = append(repoStatus.Cleaned, RepoItems{projectName, files})
+			mu.Unlock()
+		}
+	}
+
+	// -reset-hard 只重置位于目标分支上的仓库，未指定 -force 时仅列出将被丢弃的改动及提交
+	if config.ResetHard && !notOnBranch(branch)(repoPath) {
+		discarded, ok := resetHard(config.Ctx, repoPath, branch, config.Force)
+		mu.Lock()
+		switch {
+		case !ok:
+			repoStatus.ResetFailed = append(repoStatus.ResetFailed, projectName)
+			failRepo(config, repoStatus, projectName)
+		case len(discarded) > 0:
+			repoStatus.Reset = append(repoStatus.Reset, RepoItems{projectName, discarded})
+		}
+		mu.Unlock()
+	}
+
+	// -prune-merged 删除已合并到默认分支的本地分支，未指定 -force 时仅列出
+	if config.PruneMerged {
+		pruned, ok := pruneMergedBranches(config.Ctx, repoPath, branch, config.Force)
+		mu.Lock()
+		if len(pruned) > 0 {
+			repoStatus.Pruned = append(repoStatus.Pruned, RepoItems{projectName, pruned})
+		}
+		if !ok {
+			repoStatus.PruneFailed = append(repoStatus.PruneFailed, projectName)
+			failRepo(config, repoStatus, projectName)
+		}
+		mu.Unlock()
+	}
+
+	// -commit 时先提交目标分支上的未提交改动，提交后的仓库继续参与后续检查
+	if config.CommitMessage != "" && !notOnBranch(branch)(repoPath) && hasUncommittedChanges()(repoPath) &&
+		commitChanges(repoPath, config) {
+		mu.Lock()
+		repoStatus.Committed = append(repoStatus.Committed, projectName)
+		mu.Unlock()
+	}
+
+	// -push-tags 与工作区状态无关，在检查之前执行
+	if config.PushTags != "" {
+		var list *[]string
+		switch pushTags(repoPath, config) {
+		case tagsPushed:
+			list = &repoStatus.TagsPushed
+		case tagsOnRemote:
+			list = &repoStatus.TagsOnRemote
+		case tagsFailed:
+			list = &repoStatus.TagPushFailures
+		}
+		if list != nil {
+			mu.Lock()
+			*list = append(*list, projectName)
+			if list == &repoStatus.TagPushFailures {
+				failRepo(config, repoStatus, projectName)
+			}
+			mu.Unlock()
+		}
+	}
+
 	checks := []struct {
 		Check func(string) bool
 		List  *[]string
 	}{
+		{hasNoRemote(), &repoStatus.NoRemote},
 		{notOnBranch(branch), &repoStatus.NotOnBranch},
 		{hasUncommittedChanges(), &repoStatus.UncommittedChanges},
 		{hasUnpushedCommits(), &repoStatus.UnpushedCommits},
 		{noRemoteUpdates(), &repoStatus.NoUpdates},
 	}
 
+	// 仅用于提醒的只读检查，不影响是否执行 pull
+	notices := []struct {
+		Check func(string) bool
+		List  *[]string
+	}{
+		{hasStash(), &repoStatus.HasStash},
+		{hasSubmoduleDrift(), &repoStatus.SubmoduleDrift},
+	}
+
 	allPassed := true
 	for _, check := range checks {
 		if check.Check(repoPath) {
@@ -98,11 +796,135 @@ func processRepo(repoPath, branch string, repoStatus *RepoStatus, mu *sync.Mutex
 			allPassed = false
 		}
 	}
-	if allPassed && gitPull(repoPath) {
+	for _, notice := range notices {
+		if notice.Check(repoPath) {
+			mu.Lock()
+			*notice.List = append(*notice.List, projectName)
+			mu.Unlock()
+		}
+	}
+	if config.CompareFrom != "" {
+		count, ok := compareBranches(repoPath, config.CompareFrom, config.CompareTo)
+		mu.Lock()
+		if ok {
+			repoStatus.Divergence = append(repoStatus.Divergence, RepoItems{projectName, []string{count}})
+		} else {
+			repoStatus.CompareMissing = append(repoStatus.CompareMissing, projectName)
+		}
+		mu.Unlock()
+	}
+	if config.Base != "" {
+		counts, ok := compareBase(repoPath, config.Base)
+		mu.Lock()
+		if ok {
+			repoStatus.BaseDrift = append(repoStatus.BaseDrift, RepoItems{projectName, []string{counts}})
+		} else {
+			repoStatus.BaseMissing = append(repoStatus.BaseMissing, projectName)
+		}
+		mu.Unlock()
+	}
+	if config.Orphans {
+		if branches := listOrphanBranches(repoPath, branch); len(branches) > 0 {
+			mu.Lock()
+			repoStatus.OrphanBranches = append(repoStatus.OrphanBranches, RepoItems{projectName, branches})
+			mu.Unlock()
+		}
+	}
+	if config.Identity {
+		email := runGitCommand(repoPath, "config", "user.email")
+		mu.Lock()
+		switch {
+		case email == "":
+			repoStatus.MissingIdentity = append(repoStatus.MissingIdentity, projectName)
+		case config.ExpectEmail != "" && !strings.EqualFold(email, config.ExpectEmail):
+			repoStatus.WrongIdentity = append(repoStatus.WrongIdentity, RepoItems{projectName, []string{email}})
+		}
+		mu.Unlock()
+	}
+	if config.VerifySig {
+		var list *[]string
+		switch verifyHead(config.Ctx, repoPath) {
+		case sigMissing:
+			list = &repoStatus.Unsigned
+		case sigUnverifiable:
+			list = &repoStatus.SigUnverifiable
+		}
+		if list != nil {
+			mu.Lock()
+			*list = append(*list, projectName)
+			mu.Unlock()
+		}
+	}
+	if !allPassed {
+		return
+	}
+	pulled := gitPull(repoPath, config)
+	if !pulled && config.SetUpstream && setUpstream(config.Ctx, repoPath, branch) {
+		mu.Lock()
+		repoStatus.UpstreamSet = append(repoStatus.UpstreamSet, projectName)
+		mu.Unlock()
+		pulled = gitPull(repoPath, config)
+	}
+	if pulled {
 		mu.Lock()
 		repoStatus.UpdatedRepos = append(repoStatus.UpdatedRepos, projectName)
 		mu.Unlock()
+		if config.After != "" && !runExec(repoPath, config.After, config) {
+			mu.Lock()
+			repoStatus.HookFailures = append(repoStatus.HookFailures, projectName)
+			failRepo(config, repoStatus, projectName)
+			mu.Unlock()
+		}
+	} else if abortConflict(repoPath) {
+		mu.Lock()
+		repoStatus.Conflicts = append(repoStatus.Conflicts, projectName)
+		mu.Unlock()
+	} else if config.FFOnly && hasDiverged(config.Ctx, repoPath) {
+		mu.Lock()
+		repoStatus.NeedsMerge = append(repoStatus.NeedsMerge, projectName)
+		mu.Unlock()
+	}
+	if !pulled {
+		mu.Lock()
+		failRepo(config, repoStatus, projectName)
+		mu.Unlock()
+	}
+}
+
+// hasGitDirLayout 判断目录是否具有 git 目录的布局（HEAD 文件以及 objects、refs 目录），用于在调用 git 之前筛选裸仓库
+func hasGitDirLayout(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return false
 	}
+	for _, sub := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+func isBareRepo(repoPath string) bool {
+	return runGitCommand(repoPath, "rev-parse", "--is-bare-repository") == "true"
+}
+
+// fetchBareRepo 裸仓库没有工作区，不做工作区检查，仅从所有远程更新引用
+func fetchBareRepo(ctx context.Context, repoPath string) {
+	projectName := filepath.Base(repoPath)
+	if runGitCommand(repoPath, "remote") == "" {
+		return
+	}
+	if out, err := runGitAction(ctx, repoPath, "remote", "update", "--prune"); err != nil {
+		log.Printf("Failed to fetch bare repository %s: %v\n%s", projectName, err, out)
+	} else {
+		log.Printf("Fetched bare repository %s", projectName)
+	}
+}
+
+// getDefaultBranch 通过 origin/HEAD 获取仓库的默认分支，无法确定时返回空串
+func getDefaultBranch(repoPath string) string {
+	ref := runGitCommand(repoPath, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	return strings.TrimPrefix(ref, "origin/")
 }
 
 // 动态生成具体的检查函数
@@ -112,8 +934,12 @@ func notOnBranch(branch string) func(repoPath string) bool {
 	}
 }
 
+// hasUncommittedChanges 对启用 sparse-checkout 的仓库忽略 skip-worktree 的路径
 func hasUncommittedChanges() func(repoPath string) bool {
 	return func(repoPath string) bool {
+		if isSparseCheckout(repoPath) {
+			return hasSparseChanges(repoPath)
+		}
 		return runGitCommand(repoPath, "status", "--porcelain") != ""
 	}
 }
@@ -124,15 +950,87 @@ func hasUnpushedCommits() func(repoPath string) bool {
 	}
 }
 
+func hasStash() func(repoPath string) bool {
+	return func(repoPath string) bool {
+		return runGitCommand(repoPath, "stash", "list") != ""
+	}
+}
+
+// hasSubmoduleDrift 仅在存在 .gitmodules 时检查，git submodule status 中以 + 开头的子模块 HEAD 与记录的提交不同，以 - 开头的未初始化
+func hasSubmoduleDrift() func(repoPath string) bool {
+	return func(repoPath string) bool {
+		if _, err := os.Stat(filepath.Join(repoPath, ".gitmodules")); err != nil {
+			return false
+		}
+		for _, line := range strings.Split(runGitCommand(repoPath, "submodule", "status"), "\n") {
+			if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// compareBranches 统计 to 中有而 from 中没有的提交数，任一分支不存在时返回 false
+func compareBranches(repoPath, from, to string) (string, bool) {
+	for _, ref := range []string{from, to} {
+		if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}") == "" {
+			return "", false
+		}
+	}
+	count := runGitCommand(repoPath, "rev-list", "--count", from+".."+to)
+	return count, count != ""
+}
+
+// compareBase 统计当前分支相对 base 领先、落后的提交数，base 不存在时返回 false
+func compareBase(repoPath, base string) (string, bool) {
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", base+"^{commit}") == "" {
+		return "", false
+	}
+	counts := strings.Fields(runGitCommand(repoPath, "rev-list", "--left-right", "--count", "HEAD..."+base))
+	if len(counts) != 2 {
+		return "", false
+	}
+	branch := runGitCommand(repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	return fmt.Sprintf("%s: ahead %s, behind %s", branch, counts[0], counts[1]), true
+}
+
+// listOrphanBranches 列出没有上游的本地分支，当前检出的目标分支除外
+func listOrphanBranches(repoPath, branch string) []string {
+	current := runGitCommand(repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	refs := runGitCommand(repoPath, "for-each-ref", "--format=%(refname:short) %(upstream)", "refs/heads")
+	var branches []string
+	for _, line := range strings.Split(refs, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 1 || (fields[0] == current && current == branch) {
+			continue
+		}
+		branches = append(branches, fields[0])
+	}
+	return branches
+}
+
+// hasNoRemote 判断仓库是否没有配置任何远程，这类仓库只存在于本地，无需 pull
+func hasNoRemote() func(repoPath string) bool {
+	return func(repoPath string) bool {
+		return runGitCommand(repoPath, "remote") == ""
+	}
+}
+
+// noRemoteUpdates 按上游比 HEAD 多出的提交数判断，不依赖 git status 输出的语言
 func noRemoteUpdates() func(repoPath string) bool {
 	return func(repoPath string) bool {
-		return strings.Contains(runGitCommand(repoPath, "status", "-uno"), "up to date")
+		return runGitCommand(repoPath, "rev-list", "--count", "HEAD..@{u}") == "0"
 	}
 }
 
-func gitPull(repoPath string) bool {
+func gitPull(repoPath string, config *Config) bool {
 	projectName := filepath.Base(repoPath)
-	if out, err := exec.Command("git", "-C", repoPath, "pull").CombinedOutput(); err != nil {
+	args := []string{"-C", repoPath, "pull"}
+	if config.FFOnly {
+		args = append(args, "--ff-only")
+	}
+	if out, err := exec.CommandContext(config.Ctx, "git", args...).CombinedOutput(); err != nil {
 		log.Printf("Failed to pull %s: %v", projectName, err)
 		return false
 	} else {
@@ -141,6 +1039,240 @@ func gitPull(repoPath string) bool {
 	}
 }
 
+// setUpstream 在分支没有上游且 origin 上存在同名分支时设置上游，已有上游时返回 false
+func setUpstream(ctx context.Context, repoPath, branch string) bool {
+	projectName := filepath.Base(repoPath)
+	if runGitCommand(repoPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}") != "" {
+		return false
+	}
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch) == "" {
+		log.Printf("Cannot set upstream of %s: origin/%s does not exist", projectName, branch)
+		return false
+	}
+	if out, err := runGitAction(ctx, repoPath, "branch", "--set-upstream-to=origin/"+branch); err != nil {
+		log.Printf("Failed to set upstream of %s: %v\n%s", projectName, err, out)
+		return false
+	}
+	return true
+}
+
+// hasDiverged 判断 HEAD 是否已无法快进到上游
+func hasDiverged(ctx context.Context, repoPath string) bool {
+	_, err := runGitAction(ctx, repoPath, "merge-base", "--is-ancestor", "HEAD", "@{u}")
+	return err != nil
+}
+
+const (
+	checkoutDone = iota
+	checkoutMissing
+	checkoutFailed
+)
+
+const (
+	sigValid = iota
+	sigMissing
+	sigUnverifiable
+)
+
+// verifyHead 以 git verify-commit 校验 HEAD 的签名。失败时，提交对象中没有签名或 %G? 表明签名无效、过期、被吊销的
+// 归为 sigMissing；有签名但因本机缺少公钥、gpg.ssh.allowedSignersFile 等配置而无法检查的归为 sigUnverifiable
+// （未配置 allowedSignersFile 时 SSH 签名的 %G? 为 N，因此需要直接检查提交对象）
+func verifyHead(ctx context.Context, repoPath string) int {
+	if _, err := runGitAction(ctx, repoPath, "verify-commit", "HEAD"); err == nil {
+		return sigValid
+	}
+	signed := false
+	for _, line := range strings.Split(runGitCommand(repoPath, "cat-file", "commit", "HEAD"), "\n") {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "gpgsig") {
+			signed = true
+		}
+	}
+	switch runGitCommand(repoPath, "log", "-1", "--format=%G?", "HEAD") {
+	case "B", "X", "Y", "R":
+		return sigMissing
+	}
+	if !signed {
+		return sigMissing
+	}
+	return sigUnverifiable
+}
+
+const (
+	newBranchCreated = iota
+	newBranchExists
+	newBranchFailed
+)
+
+// createBranch 从当前 HEAD 创建并切换到 -new-branch 指定的分支，本地已有同名分支时不做改动；-push 时推送并设置上游
+func createBranch(repoPath string, config *Config) int {
+	projectName := filepath.Base(repoPath)
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+config.NewBranch) != "" {
+		return newBranchExists
+	}
+	if out, err := runGitAction(config.Ctx, repoPath, "checkout", "-b", config.NewBranch); err != nil {
+		log.Printf("Failed to create branch %s in %s: %v\n%s", config.NewBranch, projectName, err, out)
+		return newBranchFailed
+	}
+	if config.Push {
+		if out, err := runGitAction(config.Ctx, repoPath, "push", "-u", "origin", config.NewBranch); err != nil {
+			log.Printf("Failed to push branch %s of %s: %v\n%s", config.NewBranch, projectName, err, out)
+			return newBranchFailed
+		}
+	}
+	return newBranchCreated
+}
+
+// checkoutBranch 切换到指定分支：本地存在时直接切换，仅远程存在时创建跟踪分支，都不存在时返回 checkoutMissing
+func checkoutBranch(ctx context.Context, repoPath, branch string) int {
+	projectName := filepath.Base(repoPath)
+	args := []string{"checkout", branch}
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch) == "" {
+		if runGitCommand(repoPath, "ls-remote", "--heads", "origin", branch) == "" {
+			return checkoutMissing
+		}
+		if out, err := runGitAction(ctx, repoPath, "fetch", "origin", branch); err != nil {
+			log.Printf("Failed to fetch branch %s in %s: %v\n%s", branch, projectName, err, out)
+			return checkoutFailed
+		}
+		args = []string{"checkout", "-b", branch, "--track", "origin/" + branch}
+	}
+
+	if out, err := runGitAction(ctx, repoPath, args...); err != nil {
+		log.Printf("Failed to check out %s in %s: %v\n%s", branch, projectName, err, out)
+		return checkoutFailed
+	}
+	return checkoutDone
+}
+
+// cleanUntracked 执行 git clean -d（未确认时为 -n 预演），返回被删除或将被删除的路径；忽略的文件不受影响
+func cleanUntracked(ctx context.Context, repoPath string, force bool) []string {
+	projectName := filepath.Base(repoPath)
+	mode, prefix := "-n", "Would remove "
+	if force {
+		mode, prefix = "-f", "Removing "
+	}
+	out, err := runGitAction(ctx, repoPath, "clean", "-d", mode)
+	if err != nil {
+		log.Printf("Failed to clean %s: %v\n%s", projectName, err, out)
+		return nil
+	}
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if file, found := strings.CutPrefix(line, prefix); found {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// pruneMergedBranches 找出已合并到默认分支（无法确定时为目标分支）的本地分支，当前分支及默认分支除外；
+// 指定 force 时删除它们。返回被删除或将被删除的分支，部分分支删除失败时返回 false
+func pruneMergedBranches(ctx context.Context, repoPath, branch string, force bool) ([]string, bool) {
+	projectName := filepath.Base(repoPath)
+	base := getDefaultBranch(repoPath)
+	if base == "" {
+		base = branch
+	}
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+base) == "" {
+		log.Printf("Cannot prune %s: branch %s does not exist", projectName, base)
+		return nil, false
+	}
+
+	current := runGitCommand(repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	var merged []string
+	for _, name := range strings.Split(runGitCommand(repoPath, "branch", "--merged", base, "--format=%(refname:short)"), "\n") {
+		if name != "" && name != base && name != current {
+			merged = append(merged, name)
+		}
+	}
+	if !force || len(merged) == 0 {
+		return merged, true
+	}
+
+	// 已确认合并到默认分支，用 -D 避免 git 以当前分支为准拒绝删除
+	var pruned []string
+	ok := true
+	for _, name := range merged {
+		if out, err := runGitAction(ctx, repoPath, "branch", "-D", name); err != nil {
+			log.Printf("Failed to delete branch %s in %s: %v\n%s", name, projectName, err, out)
+			ok = false
+			continue
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned, ok
+}
+
+// commitChanges 暂存并提交所有改动，没有实际需要提交的内容时不创建空提交
+func commitChanges(repoPath string, config *Config) bool {
+	projectName := filepath.Base(repoPath)
+	if out, err := runGitAction(config.Ctx, repoPath, "add", "-A"); err != nil {
+		log.Printf("Failed to stage changes in %s: %v\n%s", projectName, err, out)
+		return false
+	}
+	if _, err := runGitAction(config.Ctx, repoPath, "diff", "--cached", "--quiet"); err == nil {
+		return false
+	}
+
+	args := []string{"commit", "-m", config.CommitMessage}
+	if config.Signoff {
+		args = append(args, "--signoff")
+	}
+	if out, err := runGitAction(config.Ctx, repoPath, args...); err != nil {
+		log.Printf("Failed to commit %s: %v\n%s", projectName, err, out)
+		return false
+	}
+
+	if config.Push {
+		if out, err := runGitAction(config.Ctx, repoPath, "push"); err != nil {
+			log.Printf("Failed to push %s: %v\n%s", projectName, err, out)
+		}
+	}
+	return true
+}
+
+// abortConflict 检测 pull 失败后遗留的合并或变基冲突，并中止以恢复干净的工作区
+func abortConflict(repoPath string) bool {
+	projectName := filepath.Base(repoPath)
+	var abortArgs []string
+	switch {
+	case runGitCommand(repoPath, "rev-parse", "-q", "--verify", "MERGE_HEAD") != "":
+		abortArgs = []string{"merge", "--abort"}
+	case gitPathExists(repoPath, "rebase-merge") || gitPathExists(repoPath, "rebase-apply"):
+		abortArgs = []string{"rebase", "--abort"}
+	default:
+		return false
+	}
+
+	// 中止操作不随 -fail-fast 取消，以免留下未完成的合并或变基
+	if out, err := exec.Command("git", append([]string{"-C", repoPath}, abortArgs...)...).CombinedOutput(); err != nil {
+		log.Printf("Failed to abort conflicted pull in %s, please resolve manually: %v\n%s", projectName, err, out)
+	} else {
+		log.Printf("Aborted conflicted pull in %s", projectName)
+	}
+	return true
+}
+
+func gitPathExists(repoPath, name string) bool {
+	path := runGitCommand(repoPath, "rev-parse", "--git-path", name)
+	if path == "" {
+		return false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoPath, path)
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// runGitAction 执行会修改仓库的 git 命令，返回合并后的输出以便记录失败原因
+func runGitAction(ctx context.Context, repoPath string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, "git", append([]string{"-C", repoPath}, args...)...).CombinedOutput()
+}
+
 func runGitCommand(repoPath string, args ...string) string {
 	cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
 	if out, err := cmd.Output(); err == nil {
@@ -149,16 +1281,169 @@ func runGitCommand(repoPath string, args ...string) string {
 	return ""
 }
 
-func printResults(branch string, repoStatus RepoStatus) {
-	printList("Repositories not on branch "+branch, repoStatus.NotOnBranch)
-	printList("Repositories with uncommitted changes", repoStatus.UncommittedChanges)
-	printList("Repositories with unpushed commits", repoStatus.UnpushedCommits)
-	printList("Repositories with no remote updates", repoStatus.NoUpdates)
-	printList("Repositories updated", repoStatus.UpdatedRepos)
+func printResults(config *Config, repoStatus RepoStatus) {
+	if config.Only != nil {
+		fmt.Printf("\nRepositories considered: %d, skipped by -only: %d\n", repoStatus.Considered, repoStatus.Skipped)
+	}
+	printList(config, colorRed, "Repositories with unreachable remotes (skipped)", repoStatus.Unreachable)
+	printHints(config, repoStatus.Paths, "git remote -v", repoStatus.Unreachable)
+	notOnBranchHeader, targetBranch := "Repositories not on branch "+config.Branch, config.Branch
+	if config.DefaultBranch {
+		notOnBranchHeader, targetBranch = "Repositories not on their default branch", "<default-branch>"
+	}
+	printList(config, colorYellow, notOnBranchHeader, repoStatus.NotOnBranch)
+	printHints(config, repoStatus.Paths, "git checkout "+targetBranch, repoStatus.NotOnBranch)
+	printList(config, colorRed, "Repositories with uncommitted changes", repoStatus.UncommittedChanges)
+	printHints(config, repoStatus.Paths, "git stash && git pull && git stash pop", repoStatus.UncommittedChanges)
+	printList(config, colorRed, "Repositories with unpushed commits", repoStatus.UnpushedCommits)
+	printHints(config, repoStatus.Paths, "git push", repoStatus.UnpushedCommits)
+	printList(config, colorGreen, "Repositories with no remote updates", repoStatus.NoUpdates)
+	printList(config, colorYellow, "Repositories with no remote (local only, not pulled)", repoStatus.NoRemote)
+	printList(config, colorGreen, "Repositories updated", repoStatus.UpdatedRepos)
+	printList(config, colorYellow, "Repositories with stashed changes", repoStatus.HasStash)
+	printHints(config, repoStatus.Paths, "git stash list", repoStatus.HasStash)
+	printList(config, colorYellow, "Repositories with submodules not at the recorded commit", repoStatus.SubmoduleDrift)
+	printHints(config, repoStatus.Paths, "git submodule update --init", repoStatus.SubmoduleDrift)
+	printList(config, colorRed, "Repositories with conflicts (pull aborted)", repoStatus.Conflicts)
+	printHints(config, repoStatus.Paths, "git pull", repoStatus.Conflicts)
+	printList(config, colorGreen, "Repositories whose upstream was set to origin", repoStatus.UpstreamSet)
+	printList(config, colorRed, "Repositories that need a manual merge (not fast-forward)", repoStatus.NeedsMerge)
+	printHints(config, repoStatus.Paths, "git pull --no-ff", repoStatus.NeedsMerge)
+	printList(config, colorRed, "Repositories whose -after command failed", repoStatus.HookFailures)
+	expectEmail := config.ExpectEmail
+	if expectEmail == "" {
+		expectEmail = "<email>"
+	}
+	printList(config, colorRed, "Repositories without user.email", repoStatus.MissingIdentity)
+	printHints(config, repoStatus.Paths, "git config user.email "+expectEmail, repoStatus.MissingIdentity)
+	printRepoItems(config, colorRed, "Repositories with user.email other than "+config.ExpectEmail, repoStatus.WrongIdentity)
+	printList(config, colorRed, "Unsigned: HEAD commit has no valid signature", repoStatus.Unsigned)
+	printList(config, colorYellow, "Repositories whose HEAD signature cannot be checked (gpg/ssh verification not configured)", repoStatus.SigUnverifiable)
+	printHints(config, repoStatus.Paths, "git verify-commit -v HEAD", repoStatus.SigUnverifiable)
+	printList(config, colorGreen, "Repositories committed", repoStatus.Committed)
+	printList(config, colorGreen, "Repositories switched branch", repoStatus.CheckedOut)
+	printList(config, colorRed, "Repositories missing the target branch", repoStatus.BranchMissing)
+	printHints(config, repoStatus.Paths, "git branch -a", repoStatus.BranchMissing)
+	printList(config, colorGreen, "Repositories switched to new branch "+config.NewBranch, repoStatus.BranchCreated)
+	printList(config, colorYellow, "Repositories that already have branch "+config.NewBranch+" (skipped)", repoStatus.BranchExists)
+	printList(config, colorRed, "Repositories failing to create or push branch "+config.NewBranch, repoStatus.BranchFailures)
+	printList(config, colorGreen, "Repositories with tags pushed", repoStatus.TagsPushed)
+	printList(config, colorGreen, "Repositories whose tags are already on the remote", repoStatus.TagsOnRemote)
+	printList(config, colorRed, "Repositories failing to push tags", repoStatus.TagPushFailures)
+	printHints(config, repoStatus.Paths, "git push origin --tags", repoStatus.TagPushFailures)
+	printList(config, colorGreen, "Bare repositories (fetched only)", repoStatus.BareRepos)
+	printList(config, colorGreen, "Repositories cloned", repoStatus.Cloned)
+	printCloneFailures(config, repoStatus.CloneFailures)
+	cleanedHeader := "Repositories with untracked files to clean (use -force to remove)"
+	if config.Force {
+		cleanedHeader = "Repositories cleaned"
+	}
+	printRepoItems(config, colorYellow, cleanedHeader, repoStatus.Cleaned)
+	resetHeader := "Repositories with local changes to discard by -reset-hard (use -force to reset)"
+	if config.Force {
+		resetHeader = "Repositories reset to origin (discarded)"
+	}
+	printRepoItems(config, colorYellow, resetHeader, repoStatus.Reset)
+	printList(config, colorRed, "Repositories failing to reset", repoStatus.ResetFailed)
+	prunedHeader := "Merged local branches to delete (use -force to delete)"
+	if config.Force {
+		prunedHeader = "Merged local branches deleted"
+	}
+	printRepoItems(config, colorYellow, prunedHeader, repoStatus.Pruned)
+	printList(config, colorRed, "Repositories failing to prune merged branches", repoStatus.PruneFailed)
+	printRepoItems(config, colorYellow, "Local branches without upstream", repoStatus.OrphanBranches)
+	printRepoItems(config, colorYellow, fmt.Sprintf("Commits in %s not in %s", config.CompareTo, config.CompareFrom), repoStatus.Divergence)
+	printList(config, colorRed, fmt.Sprintf("Repositories missing %s or %s", config.CompareFrom, config.CompareTo), repoStatus.CompareMissing)
+	printRepoItems(config, colorYellow, "Current branch compared to "+config.Base, repoStatus.BaseDrift)
+	printList(config, colorRed, "Repositories missing "+config.Base, repoStatus.BaseMissing)
+	printList(config, colorGreen, "Repositories fetched", repoStatus.Fetched)
+	printList(config, colorRed, "Repositories failing to fetch", repoStatus.FetchFailed)
+	printHints(config, repoStatus.Paths, "git fetch --all --prune", repoStatus.FetchFailed)
+	printList(config, colorGreen, "Repositories mirrored to "+config.MirrorTo, repoStatus.Mirrored)
+	printList(config, colorRed, "Repositories failing to mirror", repoStatus.MirrorFailed)
+	printHints(config, repoStatus.Paths, "git push --mirror "+mirrorRemote, repoStatus.MirrorFailed)
+	printList(config, colorGreen, "Repositories restored from snapshot", repoStatus.Restored)
+	printList(config, colorRed, "Repositories failing to restore", repoStatus.RestoreFailed)
+	printList(config, colorYellow, "Repositories not in the snapshot", repoStatus.NotInSnapshot)
+	printRepoItems(config, colorYellow, "HEAD compared with the baseline", repoStatus.BaselineDrift)
+	printList(config, colorGreen, "Repositories where the command succeeded", repoStatus.ExecSucceeded)
+	printList(config, colorRed, "Repositories where the command failed", repoStatus.ExecFailed)
+	printLog(config, repoStatus.Log)
+	if config.DiskUsage > 0 {
+		printSizes(config.DiskUsage, repoStatus.Sizes)
+	}
+	if config.Timing > 0 {
+		printTimings(config.Timing, repoStatus)
+	}
+}
+
+func printTimings(top int, repoStatus RepoStatus) {
+	timings := repoStatus.Timings
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Duration > timings[j].Duration })
+	if len(timings) > top {
+		timings = timings[:top]
+	}
+
+	fmt.Printf("\nTotal time: %s\n", repoStatus.Elapsed.Round(time.Millisecond))
+	if len(timings) > 0 {
+		fmt.Printf("Slowest repositories:\n")
+		for _, timing := range timings {
+			fmt.Printf("%10s  %s\n", timing.Duration.Round(time.Millisecond), timing.Name)
+		}
+	}
+}
+
+func printRepoItems(config *Config, color, header string, repos []RepoItems) {
+	if len(repos) == 0 {
+		return
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	fmt.Printf("\n%s:\n", colorize(config, color, header))
+	if config.Group {
+		printGroupedItems(repos)
+		return
+	}
+	for _, repo := range repos {
+		fmt.Printf("%s\t%s\n", repo.Name, strings.Join(repo.Items, ", "))
+	}
+}
+
+// printHints 在 -suggest 时为分组中的每个仓库输出进入仓库目录并执行 command 的建议命令，
+// paths 将报告中的仓库名映射为相对工作目录的路径；不使用 -group 时同名仓库无法区分，为每个同名仓库都输出建议命令
+func printHints(config *Config, paths map[string][]string, command string, items []string) {
+	if !config.Suggest {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		if len(paths[item]) == 0 {
+			fmt.Printf("  $ cd %s && %s\n", item, command)
+		}
+		for _, path := range paths[item] {
+			fmt.Printf("  $ cd %s && %s\n", path, command)
+		}
+	}
+}
+
+func printList(config *Config, color, header string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	if config.Group {
+		fmt.Printf("\n%s:\n", colorize(config, color, header))
+		printGroupedList(items)
+		return
+	}
+	fmt.Printf("\n%s:\n%s\n", colorize(config, color, header), strings.Join(items, ", "))
 }
 
-func printList(header string, items []string) {
-	if len(items) > 0 {
-		fmt.Printf("\n%s:\n%s\n", header, strings.Join(items, ", "))
+func colorize(config *Config, color, text string) string {
+	if !config.Color {
+		return text
 	}
+	return color + text + colorReset
 }
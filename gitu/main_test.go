@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// git 在 dir 中执行 git 命令，失败时终止测试；作者信息由环境变量固定，不依赖本机配置
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=gitu", "GIT_AUTHOR_EMAIL=gitu@example.com",
+		"GIT_COMMITTER_NAME=gitu", "GIT_COMMITTER_EMAIL=gitu@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+t.TempDir())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// initRepo 在 dir 中创建一个分支为 main、包含一次提交的仓库
+func initRepo(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "init", "-q", "-b", "main")
+	writeFile(t, filepath.Join(dir, "README"), "readme\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "initial")
+}
+
+// cloneWithUpstream 创建裸仓库 origin.git 及其两个克隆 name 和 other，返回两个克隆的路径
+func cloneWithUpstream(t *testing.T, base, name string) (string, string) {
+	t.Helper()
+	seed := filepath.Join(t.TempDir(), "seed")
+	initRepo(t, seed)
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	git(t, seed, "clone", "-q", "--bare", seed, origin)
+
+	repo, other := filepath.Join(base, name), filepath.Join(t.TempDir(), "other")
+	git(t, base, "clone", "-q", origin, repo)
+	git(t, base, "clone", "-q", origin, other)
+	return repo, other
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runRepo 以默认配置（目标分支 main）处理单个仓库并返回报告
+func runRepo(t *testing.T, repoPath string) RepoStatus {
+	t.Helper()
+	var repoStatus RepoStatus
+	var mu sync.Mutex
+	processRepo(repoPath, &Config{Ctx: context.Background(), Branch: "main", Parallelism: 1}, &repoStatus, &mu)
+	return repoStatus
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBehindDetection(t *testing.T) {
+	base := t.TempDir()
+	repo, other := cloneWithUpstream(t, base, "app")
+
+	t.Run("up to date", func(t *testing.T) {
+		status := runRepo(t, repo)
+		if !contains(status.NoUpdates, "app") || contains(status.UpdatedRepos, "app") {
+			t.Errorf("NoUpdates = %v, UpdatedRepos = %v; want app skipped as up to date", status.NoUpdates, status.UpdatedRepos)
+		}
+	})
+
+	t.Run("behind", func(t *testing.T) {
+		writeFile(t, filepath.Join(other, "CHANGES"), "upstream change\n")
+		git(t, other, "add", "-A")
+		git(t, other, "commit", "-q", "-m", "upstream change")
+		git(t, other, "push", "-q", "origin", "main")
+		git(t, repo, "fetch", "-q")
+
+		status := runRepo(t, repo)
+		if !contains(status.UpdatedRepos, "app") {
+			t.Fatalf("UpdatedRepos = %v, want app pulled", status.UpdatedRepos)
+		}
+		if head, upstream := git(t, repo, "rev-parse", "HEAD"), git(t, other, "rev-parse", "HEAD"); head != upstream {
+			t.Errorf("HEAD = %s after pull, want %s", head, upstream)
+		}
+	})
+
+	t.Run("ahead", func(t *testing.T) {
+		writeFile(t, filepath.Join(repo, "LOCAL"), "local change\n")
+		git(t, repo, "add", "-A")
+		git(t, repo, "commit", "-q", "-m", "local change")
+
+		status := runRepo(t, repo)
+		if !contains(status.UnpushedCommits, "app") || contains(status.UpdatedRepos, "app") {
+			t.Errorf("UnpushedCommits = %v, UpdatedRepos = %v; want app reported as unpushed", status.UnpushedCommits, status.UpdatedRepos)
+		}
+	})
+}
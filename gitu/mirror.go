@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// mirrorRemote 是 -mirror-to 使用的远程名
+const mirrorRemote = "backup"
+
+// mirrorRepo 将仓库的 backup 远程指向 baseURL/<仓库名>.git（已存在时更新地址），并以 git push --mirror 推送全部引用；
+// 设置了 -timeout 时超时会终止推送
+func mirrorRepo(repoPath string, config *Config) bool {
+	projectName := filepath.Base(repoPath)
+	url := strings.TrimSuffix(config.MirrorTo, "/") + "/" + strings.TrimSuffix(projectName, ".git") + ".git"
+	action := "add"
+	if runGitCommand(repoPath, "remote", "get-url", mirrorRemote) != "" {
+		action = "set-url"
+	}
+	if out, err := runGitAction(config.Ctx, repoPath, "remote", action, mirrorRemote, url); err != nil {
+		log.Printf("Failed to configure remote %s of %s: %v\n%s", mirrorRemote, projectName, err, out)
+		return false
+	}
+
+	ctx, cancel := commandContext(config)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", "-C", repoPath, "push", "--mirror", mirrorRemote).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Mirroring %s timed out after %s", projectName, config.Timeout)
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to mirror %s to %s: %v\n%s", projectName, url, err, out)
+		return false
+	}
+	log.Printf("Mirrored %s to %s", projectName, url)
+	return true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// previewRepo 获取上游更新但不合并，返回 HEAD..@{u} 中即将被 pull 带入的提交；没有上游时返回 nil
+func previewRepo(ctx context.Context, repoPath string) []string {
+	projectName := filepath.Base(repoPath)
+	if runGitCommand(repoPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}") == "" {
+		return nil
+	}
+	if out, err := runGitAction(ctx, repoPath, "fetch"); err != nil {
+		log.Printf("Failed to fetch %s: %v\n%s", projectName, err, out)
+		return nil
+	}
+	var commits []string
+	for _, line := range strings.Split(runGitCommand(repoPath, "log", "--oneline", "HEAD..@{u}"), "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits
+}
+
+// printPreview 按仓库名分组输出即将被 pull 带入的提交
+func printPreview(config *Config, repos []RepoItems) {
+	if len(repos) == 0 {
+		fmt.Printf("\nNo incoming commits.\n")
+		return
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	fmt.Printf("\n%s:\n", colorize(config, colorYellow, "Incoming commits"))
+	for _, repo := range repos {
+		fmt.Printf("%s (%d):\n", repo.Name, len(repo.Items))
+		for _, commit := range repo.Items {
+			fmt.Printf("  %s\n", commit)
+		}
+	}
+}
+
+// confirm 在标准输入为终端时询问是否继续，只有回答 y 或 yes 时返回 true
+func confirm(question string) bool {
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+	fmt.Printf("\n%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestPreviewRepoListReused(t *testing.T) {
+	base := t.TempDir()
+	repo, other := cloneWithUpstream(t, base, "app")
+	initRepo(t, filepath.Join(base, "skipped"))
+	writeFile(t, filepath.Join(other, "CHANGES"), "upstream change\n")
+	git(t, other, "add", "-A")
+	git(t, other, "commit", "-q", "-m", "upstream change")
+	git(t, other, "push", "-q", "origin", "main")
+
+	config := &Config{Ctx: context.Background(), Branch: "main", Parallelism: 2, Marker: ".git", Preview: true, Only: regexp.MustCompile("^app$")}
+	var previewStatus RepoStatus
+	processRepos(base, config, &previewStatus)
+	if len(previewStatus.Repos) != 1 || previewStatus.Repos[0] != repo {
+		t.Fatalf("Repos = %v, want only %s", previewStatus.Repos, repo)
+	}
+	if len(previewStatus.Preview) != 1 || previewStatus.Preview[0].Name != "app" {
+		t.Fatalf("Preview = %v, want incoming commits of app", previewStatus.Preview)
+	}
+
+	// 预览后新出现的仓库不应被处理
+	initRepo(t, filepath.Join(base, "late"))
+	config.Preview = false
+	var repoStatus RepoStatus
+	processRepoList(previewStatus.Repos, config, &repoStatus)
+	if !contains(repoStatus.UpdatedRepos, "app") {
+		t.Errorf("UpdatedRepos = %v, want app pulled", repoStatus.UpdatedRepos)
+	}
+	if contains(repoStatus.NoRemote, "late") {
+		t.Errorf("NoRemote = %v, late was not part of the preview", repoStatus.NoRemote)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoWithoutRemote(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "local")
+	initRepo(t, repo)
+
+	status := runRepo(t, repo)
+	if !contains(status.NoRemote, "local") {
+		t.Errorf("NoRemote = %v, want local", status.NoRemote)
+	}
+	if contains(status.NoUpdates, "local") || contains(status.UpdatedRepos, "local") || contains(status.Conflicts, "local") {
+		t.Errorf("remoteless repository was treated as pullable: NoUpdates = %v, UpdatedRepos = %v, Conflicts = %v",
+			status.NoUpdates, status.UpdatedRepos, status.Conflicts)
+	}
+}
+
+func TestRepoWithRemoteNotReportedAsLocal(t *testing.T) {
+	repo, _ := cloneWithUpstream(t, t.TempDir(), "app")
+
+	status := runRepo(t, repo)
+	if contains(status.NoRemote, "app") {
+		t.Errorf("NoRemote = %v, app has an origin", status.NoRemote)
+	}
+	if !contains(status.NoUpdates, "app") {
+		t.Errorf("NoUpdates = %v, want app up to date", status.NoUpdates)
+	}
+}
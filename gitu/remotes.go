@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// checkRemotes 并行检查各仓库的远程是否可达，返回可达（或没有远程）的仓库，不可达的记录到 Unreachable
+func checkRemotes(repos []string, config *Config, repoStatus *RepoStatus) []string {
+	if len(repos) == 0 {
+		return nil
+	}
+	log.Printf("Checking remotes of %d repositories", len(repos))
+
+	reachable := make([]bool, len(repos))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.Parallelism)
+	for i, repoPath := range repos {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, repoPath string) {
+			defer wg.Done()
+			reachable[i] = remoteReachable(repoPath, config)
+			<-sem
+		}(i, repoPath)
+	}
+	wg.Wait()
+
+	var result []string
+	for i, repoPath := range repos {
+		if reachable[i] {
+			result = append(result, repoPath)
+		} else {
+			repoStatus.Unreachable = append(repoStatus.Unreachable, repoName(config, repoPath))
+		}
+	}
+	return result
+}
+
+// remoteReachable 通过 git ls-remote --exit-code 探测默认远程，禁止交互式认证以免卡住；
+// 退出码 2 表示远程可达但没有引用
+func remoteReachable(repoPath string, config *Config) bool {
+	if runGitCommand(repoPath, "remote") == "" {
+		return true
+	}
+	ctx, cancel := commandContext(config)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "ls-remote", "--exit-code", "--heads")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err == nil || (ctx.Err() == nil && errors.As(err, &exitErr) && exitErr.ExitCode() == 2) {
+		return true
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Remote of %s timed out after %s", filepath.Base(repoPath), config.Timeout)
+	} else {
+		log.Printf("Remote of %s is unreachable: %v\n%s", filepath.Base(repoPath), err, out)
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// resetHard 获取 origin 后将当前分支重置到 origin/<branch>，返回被丢弃的未提交改动及本地提交；
+// 未指定 -force 时不重置，仅返回将被丢弃的内容。未跟踪的文件不受影响（见 -clean）
+func resetHard(ctx context.Context, repoPath, branch string, force bool) ([]string, bool) {
+	projectName := filepath.Base(repoPath)
+	if out, err := runGitAction(ctx, repoPath, "fetch", "origin"); err != nil {
+		log.Printf("Failed to fetch %s: %v\n%s", projectName, err, out)
+		return nil, false
+	}
+	target := "origin/" + branch
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/"+target) == "" {
+		log.Printf("Cannot reset %s: %s does not exist", projectName, target)
+		return nil, false
+	}
+
+	var discarded []string
+	for _, line := range strings.Split(runGitCommand(repoPath, "status", "--porcelain", "--untracked-files=no"), "\n") {
+		if line != "" {
+			discarded = append(discarded, line)
+		}
+	}
+	for _, line := range strings.Split(runGitCommand(repoPath, "log", "--format=%h %s", target+"..HEAD"), "\n") {
+		if line != "" {
+			discarded = append(discarded, fmt.Sprintf("commit %s", line))
+		}
+	}
+	if !force {
+		return discarded, true
+	}
+
+	if out, err := runGitAction(ctx, repoPath, "reset", "--hard", target); err != nil {
+		log.Printf("Failed to reset %s to %s: %v\n%s", projectName, target, err, out)
+		return nil, false
+	}
+	log.Printf("Reset %s to %s", projectName, target)
+	return discarded, true
+}
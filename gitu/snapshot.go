@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RepoSnapshot 记录 -snapshot 时仓库所在的提交及 origin 地址
+type RepoSnapshot struct {
+	SHA    string `json:"sha"`
+	Remote string `json:"remote,omitempty"`
+}
+
+func snapshotRepo(repoPath string) (RepoSnapshot, bool) {
+	sha := runGitCommand(repoPath, "rev-parse", "HEAD")
+	if sha == "" {
+		log.Printf("Failed to read HEAD of %s", filepath.Base(repoPath))
+		return RepoSnapshot{}, false
+	}
+	return RepoSnapshot{SHA: sha, Remote: runGitCommand(repoPath, "remote", "get-url", "origin")}, true
+}
+
+// writeSnapshot 以仓库名为键写出 JSON，键按字母顺序排列便于比较
+func writeSnapshot(path string, snapshots map[string]RepoSnapshot) error {
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readSnapshot(path string) (map[string]RepoSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots map[string]RepoSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// restoreRepo 将干净的工作区切换到快照记录的提交（分离 HEAD），本地没有该提交时先 fetch
+func restoreRepo(ctx context.Context, repoPath string, snapshot RepoSnapshot) bool {
+	projectName := filepath.Base(repoPath)
+	if hasUncommittedChanges()(repoPath) {
+		log.Printf("Not restoring %s: it has uncommitted changes", projectName)
+		return false
+	}
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", snapshot.SHA+"^{commit}") == "" {
+		if out, err := runGitAction(ctx, repoPath, "fetch", "--all"); err != nil {
+			log.Printf("Failed to fetch %s: %v\n%s", projectName, err, out)
+			return false
+		}
+	}
+	if out, err := runGitAction(ctx, repoPath, "checkout", "--detach", snapshot.SHA); err != nil {
+		log.Printf("Failed to restore %s to %s: %v\n%s", projectName, snapshot.SHA, err, out)
+		return false
+	}
+	return true
+}
+
+// compareBaseline 描述 HEAD 相对 -baseline 快照中记录的提交的变化：未变、前进、后退或分叉
+func compareBaseline(repoPath, sha string) string {
+	if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", sha+"^{commit}") == "" {
+		return "baseline commit " + sha + " not found"
+	}
+	counts := strings.Fields(runGitCommand(repoPath, "rev-list", "--left-right", "--count", sha+"...HEAD"))
+	if len(counts) != 2 {
+		return "cannot compare with " + sha
+	}
+	behind, ahead := counts[0], counts[1]
+	switch {
+	case behind == "0" && ahead == "0":
+		return "unchanged"
+	case behind == "0":
+		return "forward by " + ahead + " commit(s)"
+	case ahead == "0":
+		return "backward by " + behind + " commit(s)"
+	default:
+		return "diverged (" + ahead + " ahead, " + behind + " behind)"
+	}
+}
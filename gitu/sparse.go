@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+)
+
+// isSparseCheckout 判断仓库是否启用了 sparse-checkout
+func isSparseCheckout(repoPath string) bool {
+	return runGitCommand(repoPath, "config", "--bool", "core.sparseCheckout") == "true"
+}
+
+// skipWorktreePaths 返回 git ls-files -v 中标记为 skip-worktree（S 或 s）的路径，即 sparse-checkout 集合之外的文件
+func skipWorktreePaths(repoPath string) map[string]bool {
+	paths := make(map[string]bool)
+	for _, record := range strings.Split(runGitCommand(repoPath, "ls-files", "-v", "-z"), "\x00") {
+		// 同时标记为 assume-unchanged 时标记为小写的 s
+		if len(record) > 2 && (record[0] == 'S' || record[0] == 's') && record[1] == ' ' {
+			paths[record[2:]] = true
+		}
+	}
+	return paths
+}
+
+// hasSparseChanges 解析 git status --porcelain=v2 -z 的输出，忽略 git 标记为 skip-worktree 的路径
+func hasSparseChanges(repoPath string) bool {
+	skipped := skipWorktreePaths(repoPath)
+	records := strings.Split(runGitCommand(repoPath, "status", "--porcelain=v2", "-z"), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		var file string
+		switch {
+		case strings.HasPrefix(record, "1 "):
+			file = field(record, 8)
+		case strings.HasPrefix(record, "2 "):
+			// 重命名或复制的记录之后紧跟原路径
+			file = field(record, 9)
+			i++
+		case strings.HasPrefix(record, "u "):
+			file = field(record, 10)
+		case strings.HasPrefix(record, "? "):
+			file = record[2:]
+		default:
+			continue
+		}
+		if !skipped[file] {
+			return true
+		}
+	}
+	return false
+}
+
+// field 返回以空格分隔的第 n 个字段之后的全部内容（从 0 开始计数）
+func field(record string, n int) string {
+	parts := strings.SplitN(record, " ", n+1)
+	if len(parts) <= n {
+		return ""
+	}
+	return parts[n]
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sparseRepo 创建包含 a/ 与 b/ 两个目录的仓库，并以 cone 模式只检出 a/
+func sparseRepo(t *testing.T) string {
+	t.Helper()
+	repo := filepath.Join(t.TempDir(), "sparse")
+	initRepo(t, repo)
+	for _, dir := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(repo, dir, "file.txt"), dir+"\n")
+	}
+	git(t, repo, "add", "-A")
+	git(t, repo, "commit", "-q", "-m", "add a and b")
+	git(t, repo, "sparse-checkout", "set", "a")
+	if _, err := os.Stat(filepath.Join(repo, "b", "file.txt")); !os.IsNotExist(err) {
+		t.Fatalf("b/file.txt should be outside the sparse checkout: %v", err)
+	}
+	return repo
+}
+
+func TestSparseCheckoutClean(t *testing.T) {
+	repo := sparseRepo(t)
+	if !isSparseCheckout(repo) {
+		t.Fatal("isSparseCheckout = false")
+	}
+	if !skipWorktreePaths(repo)["b/file.txt"] {
+		t.Errorf("skipWorktreePaths = %v, want b/file.txt", skipWorktreePaths(repo))
+	}
+	if hasUncommittedChanges()(repo) {
+		t.Error("clean sparse checkout reported as having uncommitted changes")
+	}
+}
+
+func TestSparseCheckoutDirty(t *testing.T) {
+	for name, change := range map[string]func(t *testing.T, repo string){
+		"modified":  func(t *testing.T, repo string) { writeFile(t, filepath.Join(repo, "a", "file.txt"), "changed\n") },
+		"untracked": func(t *testing.T, repo string) { writeFile(t, filepath.Join(repo, "a", "new.txt"), "new\n") },
+		"deleted": func(t *testing.T, repo string) {
+			if err := os.Remove(filepath.Join(repo, "README")); err != nil {
+				t.Fatal(err)
+			}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := sparseRepo(t)
+			change(t, repo)
+			if !hasUncommittedChanges()(repo) {
+				t.Errorf("%s file inside the sparse checkout not reported", name)
+			}
+		})
+	}
+}
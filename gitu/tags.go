@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pushAllTags 为 -push-tags 的特殊取值，表示推送所有本地标签
+const pushAllTags = "all"
+
+const (
+	tagsNone = iota
+	tagsPushed
+	tagsOnRemote
+	tagsFailed
+)
+
+// pushTags 将本地标签（全部或 -push-tags 指定的一个）推送到 origin，远程已有的标签不再推送；
+// 本地没有相应标签时返回 tagsNone，全部已在远程时返回 tagsOnRemote
+func pushTags(repoPath string, config *Config) int {
+	projectName := filepath.Base(repoPath)
+	var localTags []string
+	if config.PushTags == pushAllTags {
+		if tags := runGitCommand(repoPath, "tag", "--list"); tags != "" {
+			localTags = strings.Split(tags, "\n")
+		}
+	} else if runGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "refs/tags/"+config.PushTags) != "" {
+		localTags = []string{config.PushTags}
+	}
+	if len(localTags) == 0 {
+		return tagsNone
+	}
+
+	remoteTags, err := listRemoteTags(config.Ctx, repoPath)
+	if err != nil {
+		log.Printf("Failed to list remote tags of %s: %v", projectName, err)
+		return tagsFailed
+	}
+	var refs []string
+	for _, tag := range localTags {
+		if !remoteTags[tag] {
+			refs = append(refs, "refs/tags/"+tag)
+		}
+	}
+	if len(refs) == 0 {
+		return tagsOnRemote
+	}
+
+	if out, err := runGitAction(config.Ctx, repoPath, append([]string{"push", "origin"}, refs...)...); err != nil {
+		log.Printf("Failed to push tags of %s: %v\n%s", projectName, err, out)
+		return tagsFailed
+	}
+	log.Printf("Pushed %d tag(s) of %s", len(refs), projectName)
+	return tagsPushed
+}
+
+// listRemoteTags 通过 git ls-remote --tags 获取 origin 上已有的标签名
+func listRemoteTags(ctx context.Context, repoPath string) (map[string]bool, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", repoPath, "ls-remote", "--tags", "origin").Output()
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		// 附注标签会额外列出一行以 ^{} 结尾的解引用结果
+		name := strings.TrimSuffix(strings.TrimPrefix(fields[1], "refs/tags/"), "^{}")
+		tags[name] = true
+	}
+	return tags, nil
+}
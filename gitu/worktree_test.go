@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkedWorktreeDiscovered(t *testing.T) {
+	base := t.TempDir()
+	repo := filepath.Join(base, "app")
+	initRepo(t, repo)
+	worktree := filepath.Join(base, "app-feature")
+	git(t, repo, "worktree", "add", "-q", "-b", "feature", worktree)
+
+	if info, err := os.Stat(filepath.Join(worktree, ".git")); err != nil || info.IsDir() {
+		t.Fatalf("expected %s/.git to be a file: %v", worktree, err)
+	}
+	if resolveGitFile(filepath.Join(worktree, ".git")) == "" {
+		t.Fatalf("resolveGitFile did not resolve the worktree git dir")
+	}
+
+	var status RepoStatus
+	processRepos(base, &Config{Ctx: context.Background(), Branch: "main", Parallelism: 2, Marker: ".git"}, &status)
+	if status.Considered != 2 {
+		t.Errorf("Considered = %d, want 2 (repository and its linked worktree)", status.Considered)
+	}
+	if !contains(status.NotOnBranch, "app-feature") {
+		t.Errorf("NotOnBranch = %v, want the worktree on branch feature to be checked", status.NotOnBranch)
+	}
+	if contains(status.NotOnBranch, "app") {
+		t.Errorf("NotOnBranch = %v, main repository is on main", status.NotOnBranch)
+	}
+}
+
+func TestSubmoduleNotDiscovered(t *testing.T) {
+	base := t.TempDir()
+	lib := filepath.Join(t.TempDir(), "lib")
+	initRepo(t, lib)
+	app := filepath.Join(base, "app")
+	initRepo(t, app)
+	git(t, app, "-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "lib")
+	git(t, app, "commit", "-qm", "add lib")
+
+	if info, err := os.Stat(filepath.Join(app, "lib", ".git")); err != nil || info.IsDir() {
+		t.Fatalf("expected %s/lib/.git to be a file: %v", app, err)
+	}
+
+	var status RepoStatus
+	processRepos(base, &Config{Ctx: context.Background(), Branch: "main", Parallelism: 2, Marker: ".git"}, &status)
+	if status.Considered != 1 {
+		t.Errorf("Considered = %d, want 1 (submodule is part of its superproject)", status.Considered)
+	}
+}
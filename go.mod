@@ -2,4 +2,10 @@ module gobin
 
 go 1.20
 
-require github.com/dlclark/regexp2 v1.11.4
+require (
+	github.com/dlclark/regexp2 v1.11.4
+	github.com/fsnotify/fsnotify v1.9.0
+	golang.org/x/text v0.14.0
+)
+
+require golang.org/x/sys v0.13.0 // indirect